@@ -0,0 +1,64 @@
+package cc2500
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// When address checking is enabled, the first byte of each packet
+// (after the length byte, if any) is the destination address,
+// and the hardware discards packets addressed to other devices.
+
+// Broadcast addresses, accepted by the AddressCheck00Broadcast
+// and AddressCheck00FFBroadcast modes regardless of the device address.
+const (
+	Broadcast00 = 0x00
+	BroadcastFF = 0xFF
+)
+
+// ReadAddressCheck returns the radio's address filtering mode.
+func (r *Radio) ReadAddressCheck() AddressCheck {
+	return AddressCheck(r.hw.ReadRegister(PKTCTRL1) & PKTCTRL1_ADR_CHK_MASK)
+}
+
+// SetAddressCheck sets the radio's address filtering mode.
+func (r *Radio) SetAddressCheck(mode AddressCheck) {
+	if mode > AddressCheck00FFBroadcast {
+		r.SetError(fmt.Errorf("invalid address check mode %d", mode))
+		return
+	}
+	p1 := r.hw.ReadRegister(PKTCTRL1) &^ PKTCTRL1_ADR_CHK_MASK
+	r.hw.WriteRegister(PKTCTRL1, p1|byte(mode))
+}
+
+// ReadAddress returns the radio's device address.
+func (r *Radio) ReadAddress() byte {
+	return r.hw.ReadRegister(ADDR)
+}
+
+// SetAddress sets the radio's device address.
+func (r *Radio) SetAddress(addr byte) {
+	r.hw.WriteRegister(ADDR, addr)
+}
+
+// SendTo transmits the given packet to the given destination address.
+func (r *Radio) SendTo(addr byte, data []byte) {
+	r.Send(append([]byte{addr}, data...))
+}
+
+// ReceiveAddressed is like ReceiveContext for packets that begin with
+// a destination address, which is returned separately from the payload.
+// With address checking enabled, it is either the device address
+// or a broadcast address.
+func (r *Radio) ReceiveAddressed(ctx context.Context, timeout time.Duration) (byte, []byte, int, uint8) {
+	data, rssi, lqi := r.ReceiveContext(ctx, timeout)
+	if r.Error() != nil {
+		return 0, nil, rssi, lqi
+	}
+	if len(data) == 0 {
+		r.SetError(LengthError{Data: data, Expected: 1, RSSI: rssi})
+		return 0, nil, rssi, lqi
+	}
+	return data[0], data[1:], rssi, lqi
+}
@@ -0,0 +1,81 @@
+package cc2500
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSetAddressCheck(t *testing.T) {
+	r, _ := openSimulator(t)
+	for _, mode := range []AddressCheck{AddressCheckNoBroadcast, AddressCheck00Broadcast, AddressCheck00FFBroadcast, AddressCheckNone} {
+		r.SetAddressCheck(mode)
+		if got := r.ReadAddressCheck(); got != mode {
+			t.Errorf("ReadAddressCheck() == %d, want %d", got, mode)
+		}
+	}
+	if r.hw.ReadRegister(PKTCTRL1)&PKTCTRL1_APPEND_STATUS == 0 {
+		t.Errorf("SetAddressCheck() changed other PKTCTRL1 fields")
+	}
+	r.SetAddress(0x42)
+	if got := r.ReadAddress(); got != 0x42 {
+		t.Errorf("ReadAddress() == %02X, want 42", got)
+	}
+	r.SetAddressCheck(4)
+	if r.Error() == nil {
+		t.Errorf("SetAddressCheck(4) succeeded")
+	}
+}
+
+func TestSendTo(t *testing.T) {
+	r, s := openSimulator(t)
+	r.SendTo(0x42, p2)
+	if r.Error() != nil {
+		t.Fatal(r.Error())
+	}
+	want := append([]byte{0x42}, p2...)
+	sent := s.Transmitted()
+	if len(sent) != 1 || !bytes.Equal(sent[0], want) {
+		t.Errorf("Transmitted() == % X, want [% X]", sent, want)
+	}
+}
+
+func TestReceiveAddressed(t *testing.T) {
+	cases := []struct {
+		mode     AddressCheck
+		addr     byte
+		accepted bool
+	}{
+		{AddressCheckNone, 0x17, true},
+		{AddressCheckNoBroadcast, 0x42, true},
+		{AddressCheckNoBroadcast, 0x17, false},
+		{AddressCheckNoBroadcast, Broadcast00, false},
+		{AddressCheck00Broadcast, Broadcast00, true},
+		{AddressCheck00Broadcast, BroadcastFF, false},
+		{AddressCheck00FFBroadcast, Broadcast00, true},
+		{AddressCheck00FFBroadcast, BroadcastFF, true},
+		{AddressCheck00FFBroadcast, 0x17, false},
+	}
+	payload := []byte{1, 2, 3, 4}
+	for _, c := range cases {
+		r, s := openSimulator(t)
+		r.SetAddress(0x42)
+		r.SetAddressCheck(c.mode)
+		s.Inject(SimPacket{Data: append([]byte{c.addr}, payload...), RSSI: -60})
+		addr, data, _, _ := r.ReceiveAddressed(context.Background(), 20*time.Millisecond)
+		if !c.accepted {
+			if r.Error() != ErrReceiveTimeout {
+				t.Errorf("mode %d: packet for %02X: error == %v, want %v", c.mode, c.addr, r.Error(), ErrReceiveTimeout)
+			}
+			continue
+		}
+		if r.Error() != nil {
+			t.Errorf("mode %d: packet for %02X: %v", c.mode, c.addr, r.Error())
+			continue
+		}
+		if addr != c.addr || !bytes.Equal(data, payload) {
+			t.Errorf("mode %d: ReceiveAddressed() == %02X, % X, want %02X, % X", c.mode, addr, data, c.addr, payload)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package cc2500
+
+// Register value calculators for the exponent/mantissa pairs
+// used by the modem configuration registers (data sheet section 13).
+// Each one returns the exponent and mantissa of the closest setting
+// to the requested value, and the value actually achieved.
+
+func abs64(x int64) int64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// closest searches all exponent and mantissa values
+// for the one whose value is closest to want.
+func closest(want uint32, maxExp, maxMant int, value func(e, m int) uint32) (byte, byte, uint32) {
+	bestE, bestM, best := 0, 0, value(0, 0)
+	for e := 0; e <= maxExp; e++ {
+		for m := 0; m <= maxMant; m++ {
+			v := value(e, m)
+			if abs64(int64(v)-int64(want)) < abs64(int64(best)-int64(want)) {
+				bestE, bestM, best = e, m, v
+			}
+		}
+	}
+	return byte(bestE), byte(bestM), best
+}
+
+func dataRate(e, m int) uint32 {
+	return uint32(((256 + uint64(m)) << uint(e) * FXOSC) >> 28)
+}
+
+func channelBandwidth(e, m int) uint32 {
+	return uint32(FXOSC / ((4 + uint64(m)) << uint(e+3)))
+}
+
+func deviation(e, m int) uint32 {
+	return uint32(((8 + uint64(m)) << uint(e) * FXOSC) >> 17)
+}
+
+func channelSpacing(e, m int) uint32 {
+	return uint32(((256 + uint64(m)) << uint(e) * FXOSC) >> 18)
+}
+
+// DataRateRegisters returns the DRATE_E and DRATE_M values
+// for the given data rate in Baud.
+func DataRateRegisters(rate uint32) (byte, byte, uint32) {
+	return closest(rate, 15, 255, dataRate)
+}
+
+// BandwidthRegisters returns the CHANBW_E and CHANBW_M values
+// for the given channel filter bandwidth in Hertz.
+func BandwidthRegisters(bw uint32) (byte, byte, uint32) {
+	return closest(bw, 3, 3, channelBandwidth)
+}
+
+// DeviationRegisters returns the DEVIATION_E and DEVIATION_M values
+// for the given frequency deviation in Hertz.
+func DeviationRegisters(dev uint32) (byte, byte, uint32) {
+	return closest(dev, 7, 7, deviation)
+}
+
+// ChannelSpacingRegisters returns the CHANSPC_E and CHANSPC_M values
+// for the given channel spacing in Hertz.
+func ChannelSpacingRegisters(spacing uint32) (byte, byte, uint32) {
+	return closest(spacing, 3, 255, channelSpacing)
+}
@@ -0,0 +1,344 @@
+// Package calibration converts the raw and filtered sensor values
+// in Dexcom G4 packets into estimated glucose values,
+// using fingerstick meter readings to calibrate the sensor.
+package calibration
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ecc1/cc2500"
+)
+
+const (
+	// WarmupPeriod is the time after a sensor change
+	// before meter readings are accepted.
+	WarmupPeriod = 2 * time.Hour
+
+	// MinCalibrations is the number of meter readings required
+	// after a sensor change before glucose values are estimated.
+	MinCalibrations = 2
+
+	// Maximum number of meter readings used in the fit.
+	maxCalibrations = 6
+
+	// Maximum time between a meter reading and the sensor reading paired with it.
+	maxPairingGap = 10 * time.Minute
+
+	// Range of plausible sensor sensitivity, in counts per mg/dL.
+	minSlope = 400
+	maxSlope = 2000
+
+	// Minimum range of meter values for a two-parameter fit, in mg/dL.
+	minFitRange = 20
+
+	// Acceptable meter values, in mg/dL.
+	minMeterGlucose = 20
+	maxMeterGlucose = 600
+
+	// Range of estimated glucose values, in mg/dL.
+	// Estimates outside this range are clamped, as on a G4 receiver.
+	MinGlucose = 40
+	MaxGlucose = 400
+
+	// Time span of sensor readings used to compute the rate of change.
+	trendWindow = 16 * time.Minute
+
+	// Number of sensor readings kept for pairing and trend computation.
+	historySize = 12
+)
+
+var (
+	// ErrUncalibrated indicates that too few meter readings
+	// have been entered since the last sensor change.
+	ErrUncalibrated = errors.New("sensor is not calibrated")
+
+	// ErrWarmup indicates a meter reading entered during sensor warmup.
+	ErrWarmup = errors.New("sensor is warming up")
+
+	// ErrNoSensorReading indicates that there is no sensor reading
+	// close enough in time to pair with a meter reading.
+	ErrNoSensorReading = errors.New("no sensor reading near meter reading")
+
+	// ErrNoPacket indicates a nil packet passed to Update.
+	ErrNoPacket = errors.New("no packet")
+)
+
+// MeterReading represents a fingerstick blood glucose measurement.
+type MeterReading struct {
+	Time    time.Time
+	Glucose float64 // mg/dL
+}
+
+// Estimate represents an estimated glucose value.
+type Estimate struct {
+	Time         time.Time
+	Glucose      float64 // mg/dL, from the filtered sensor value
+	Unfiltered   float64 // mg/dL, from the raw sensor value
+	RateOfChange float64 // mg/dL per minute
+	Trend        Trend
+	Packet       *cc2500.Packet
+}
+
+type sensorReading struct {
+	time     time.Time
+	raw      float64
+	filtered float64
+}
+
+type calibrationPoint struct {
+	time    time.Time
+	raw     float64 // sensor value paired with meter reading
+	glucose float64
+}
+
+// Calibrator fits a linear model (sensor value = slope * glucose + intercept)
+// to meter readings paired with sensor readings,
+// and uses it to estimate glucose values.
+// It is safe for concurrent use.
+type Calibrator struct {
+	mu          sync.Mutex
+	sensorStart time.Time
+	history     []sensorReading
+	pending     []MeterReading
+	unpaired    []MeterReading
+	points      []calibrationPoint
+	slope       float64
+	intercept   float64
+}
+
+// New returns a Calibrator with no sensor change or meter readings.
+// Meter readings are accepted immediately, as if the sensor
+// had been inserted long ago.
+func New() *Calibrator {
+	return &Calibrator{}
+}
+
+// SensorChange records the insertion of a new sensor at the given time,
+// discarding all previous calibration.
+func (c *Calibrator) SensorChange(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sensorStart = t
+	c.history = nil
+	c.pending = nil
+	c.unpaired = nil
+	c.points = nil
+	c.slope = 0
+	c.intercept = 0
+}
+
+// Calibrated reports whether enough meter readings have been entered
+// since the last sensor change to estimate glucose values.
+func (c *Calibrator) Calibrated() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calibrated()
+}
+
+func (c *Calibrator) calibrated() bool {
+	return len(c.points) >= MinCalibrations
+}
+
+// Parameters returns the current slope (counts per mg/dL) and intercept (counts).
+func (c *Calibrator) Parameters() (float64, float64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.calibrated() {
+		return 0, 0, ErrUncalibrated
+	}
+	return c.slope, c.intercept, nil
+}
+
+// AddMeterReading enters a fingerstick meter reading.
+// It is paired with the closest sensor reading within 10 minutes;
+// if there is none yet, it is paired with the next sensor reading to arrive
+// within 10 minutes of it, or else reported by Unpaired.
+func (c *Calibrator) AddMeterReading(m MeterReading) error {
+	if m.Glucose < minMeterGlucose || m.Glucose > maxMeterGlucose {
+		return fmt.Errorf("meter reading %g mg/dL out of range", m.Glucose)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.sensorStart.IsZero() && m.Time.Before(c.sensorStart.Add(WarmupPeriod)) {
+		return ErrWarmup
+	}
+	s, ok := c.closestReading(m.Time)
+	if !ok {
+		if len(c.history) != 0 && m.Time.Before(c.history[len(c.history)-1].time) {
+			return ErrNoSensorReading
+		}
+		c.pending = append(c.pending, m)
+		return nil
+	}
+	c.addPoint(m, s)
+	return nil
+}
+
+// Unpaired returns the meter readings that were discarded since the
+// last call because no sensor reading arrived within 10 minutes of them,
+// such as readings older than the first sensor reading received.
+func (c *Calibrator) Unpaired() []MeterReading {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v := c.unpaired
+	c.unpaired = nil
+	return v
+}
+
+func (c *Calibrator) closestReading(t time.Time) (sensorReading, bool) {
+	best := sensorReading{}
+	bestGap := maxPairingGap + 1
+	for _, s := range c.history {
+		gap := s.time.Sub(t)
+		if gap < 0 {
+			gap = -gap
+		}
+		if gap < bestGap {
+			best, bestGap = s, gap
+		}
+	}
+	return best, bestGap <= maxPairingGap
+}
+
+func (c *Calibrator) addPoint(m MeterReading, s sensorReading) {
+	c.points = append(c.points, calibrationPoint{time: m.Time, raw: s.filtered, glucose: m.Glucose})
+	if len(c.points) > maxCalibrations {
+		c.points = c.points[len(c.points)-maxCalibrations:]
+	}
+	c.fit()
+}
+
+// Least-squares fit of sensor value against meter glucose.
+// If the meter values span too small a range for the slope
+// to be meaningful, or the fitted slope is implausible,
+// the slope is clamped and the intercept chosen to pass through the mean.
+func (c *Calibrator) fit() {
+	n := float64(len(c.points))
+	var sumX, sumY float64
+	minX, maxX := math.Inf(1), math.Inf(-1)
+	for _, p := range c.points {
+		sumX += p.glucose
+		sumY += p.raw
+		minX = math.Min(minX, p.glucose)
+		maxX = math.Max(maxX, p.glucose)
+	}
+	meanX, meanY := sumX/n, sumY/n
+	slope := meanY / meanX
+	if maxX-minX >= minFitRange {
+		var sxx, sxy float64
+		for _, p := range c.points {
+			dx := p.glucose - meanX
+			sxx += dx * dx
+			sxy += dx * (p.raw - meanY)
+		}
+		slope = sxy / sxx
+	}
+	slope = math.Max(minSlope, math.Min(maxSlope, slope))
+	c.slope = slope
+	c.intercept = meanY - slope*meanX
+}
+
+// Update records the sensor values in the given packet and returns
+// the corresponding glucose estimate, or ErrUncalibrated.
+func (c *Calibrator) Update(p *cc2500.Packet) (*Estimate, error) {
+	if p == nil {
+		return nil, ErrNoPacket
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := sensorReading{time: p.Timestamp, raw: float64(p.Raw), filtered: float64(p.Filtered)}
+	c.history = append(c.history, s)
+	if len(c.history) > historySize {
+		c.history = c.history[len(c.history)-historySize:]
+	}
+	var pending []MeterReading
+	for _, m := range c.pending {
+		gap := s.time.Sub(m.Time)
+		switch {
+		case gap < 0 && -gap > maxPairingGap:
+			// Wait for a sensor reading closer to the meter reading.
+			pending = append(pending, m)
+		case gap > maxPairingGap:
+			c.unpaired = append(c.unpaired, m)
+		default:
+			c.addPoint(m, s)
+		}
+	}
+	c.pending = pending
+	if !c.calibrated() {
+		return nil, ErrUncalibrated
+	}
+	e := Estimate{
+		Time:       p.Timestamp,
+		Glucose:    c.glucose(s.filtered),
+		Unfiltered: c.glucose(s.raw),
+		Packet:     p,
+	}
+	e.RateOfChange, e.Trend = c.trend(s)
+	return &e, nil
+}
+
+func (c *Calibrator) glucose(v float64) float64 {
+	g := (v - c.intercept) / c.slope
+	return math.Max(MinGlucose, math.Min(MaxGlucose, g))
+}
+
+// Compute the rate of change by least-squares fit of the
+// estimated glucose values over the trend window ending at s.
+func (c *Calibrator) trend(s sensorReading) (float64, Trend) {
+	var ts, gs []float64
+	for _, h := range c.history {
+		age := s.time.Sub(h.time)
+		if age < 0 || age > trendWindow {
+			continue
+		}
+		ts = append(ts, -age.Minutes())
+		gs = append(gs, (h.filtered-c.intercept)/c.slope)
+	}
+	if len(ts) < 2 {
+		return 0, NotComputable
+	}
+	n := float64(len(ts))
+	var sumT, sumG float64
+	for i := range ts {
+		sumT += ts[i]
+		sumG += gs[i]
+	}
+	meanT, meanG := sumT/n, sumG/n
+	var stt, stg float64
+	for i := range ts {
+		dt := ts[i] - meanT
+		stt += dt * dt
+		stg += dt * (gs[i] - meanG)
+	}
+	if stt == 0 {
+		return 0, NotComputable
+	}
+	rate := stg / stt
+	return rate, TrendFromRate(rate)
+}
+
+// Stream converts a stream of packets into a stream of glucose estimates,
+// omitting nil packets and packets received while uncalibrated.
+// The returned channel is closed when the input channel is closed.
+func (c *Calibrator) Stream(packets <-chan *cc2500.Packet) <-chan *Estimate {
+	estimates := make(chan *Estimate, 10)
+	go func() {
+		defer close(estimates)
+		for p := range packets {
+			if p == nil {
+				continue
+			}
+			e, err := c.Update(p)
+			if err != nil {
+				continue
+			}
+			estimates <- e
+		}
+	}()
+	return estimates
+}
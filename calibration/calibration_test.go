@@ -0,0 +1,199 @@
+package calibration
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ecc1/cc2500"
+)
+
+var t0 = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Sensor with slope 1000 counts per mg/dL and intercept 30000 counts.
+func packetFor(t time.Time, glucose float64) *cc2500.Packet {
+	v := uint32(1000*glucose + 30000)
+	return &cc2500.Packet{Timestamp: t, Raw: v, Filtered: v}
+}
+
+func TestUncalibrated(t *testing.T) {
+	c := New()
+	_, err := c.Update(packetFor(t0, 100))
+	if err != ErrUncalibrated {
+		t.Errorf("Update() error == %v, want %v", err, ErrUncalibrated)
+	}
+	err = c.AddMeterReading(MeterReading{Time: t0, Glucose: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Update(packetFor(t0.Add(5*time.Minute), 100))
+	if err != ErrUncalibrated {
+		t.Errorf("Update() error == %v, want %v", err, ErrUncalibrated)
+	}
+}
+
+func TestUpdateNil(t *testing.T) {
+	c := New()
+	_, err := c.Update(nil)
+	if err != ErrNoPacket {
+		t.Errorf("Update(nil) error == %v, want %v", err, ErrNoPacket)
+	}
+}
+
+func TestCalibration(t *testing.T) {
+	c := New()
+	c.Update(packetFor(t0, 100))
+	c.AddMeterReading(MeterReading{Time: t0.Add(time.Minute), Glucose: 100})
+	c.Update(packetFor(t0.Add(5*time.Minute), 200))
+	c.AddMeterReading(MeterReading{Time: t0.Add(6 * time.Minute), Glucose: 200})
+	slope, intercept, err := c.Parameters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(slope-1000) > 1e-6 || math.Abs(intercept-30000) > 1e-3 {
+		t.Errorf("Parameters() == %g, %g; want 1000, 30000", slope, intercept)
+	}
+	e, err := c.Update(packetFor(t0.Add(10*time.Minute), 150))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(e.Glucose-150) > 0.01 {
+		t.Errorf("Glucose == %g, want 150", e.Glucose)
+	}
+}
+
+func TestPendingMeterReading(t *testing.T) {
+	c := New()
+	c.AddMeterReading(MeterReading{Time: t0, Glucose: 80})
+	c.Update(packetFor(t0.Add(2*time.Minute), 80))
+	c.AddMeterReading(MeterReading{Time: t0.Add(3 * time.Minute), Glucose: 160})
+	c.Update(packetFor(t0.Add(7*time.Minute), 160))
+	if !c.Calibrated() {
+		t.Errorf("Calibrated() == false after pairing pending meter readings")
+	}
+}
+
+func TestPendingMeterReadingOrder(t *testing.T) {
+	c := New()
+	// Entered before any sensor readings are known.
+	old := MeterReading{Time: t0, Glucose: 90}
+	later := MeterReading{Time: t0.Add(time.Hour), Glucose: 160}
+	c.AddMeterReading(old)
+	c.AddMeterReading(MeterReading{Time: t0.Add(30 * time.Minute), Glucose: 80})
+	c.AddMeterReading(later)
+	c.Update(packetFor(t0.Add(28*time.Minute), 80))
+	if c.Calibrated() {
+		t.Fatal("Calibrated() == true after one pairing")
+	}
+	if v := c.Unpaired(); len(v) != 1 || v[0] != old {
+		t.Errorf("Unpaired() == %v, want [%v]", v, old)
+	}
+	// The later reading is still pending.
+	c.Update(packetFor(t0.Add(63*time.Minute), 160))
+	if !c.Calibrated() {
+		t.Errorf("Calibrated() == false after pairing a later pending reading")
+	}
+	if v := c.Unpaired(); len(v) != 0 {
+		t.Errorf("Unpaired() == %v, want none", v)
+	}
+}
+
+func TestSensorChange(t *testing.T) {
+	c := New()
+	c.Update(packetFor(t0, 100))
+	c.AddMeterReading(MeterReading{Time: t0, Glucose: 100})
+	c.Update(packetFor(t0.Add(5*time.Minute), 200))
+	c.AddMeterReading(MeterReading{Time: t0.Add(5 * time.Minute), Glucose: 200})
+	if !c.Calibrated() {
+		t.Fatal("not calibrated")
+	}
+	start := t0.Add(10 * time.Minute)
+	c.SensorChange(start)
+	if c.Calibrated() {
+		t.Errorf("Calibrated() == true after sensor change")
+	}
+	_, err := c.Update(packetFor(start.Add(5*time.Minute), 120))
+	if err != ErrUncalibrated {
+		t.Errorf("Update() error == %v, want %v", err, ErrUncalibrated)
+	}
+	err = c.AddMeterReading(MeterReading{Time: start.Add(time.Hour), Glucose: 120})
+	if err != ErrWarmup {
+		t.Errorf("AddMeterReading() during warmup error == %v, want %v", err, ErrWarmup)
+	}
+}
+
+func TestSlopeClamping(t *testing.T) {
+	c := New()
+	c.Update(packetFor(t0, 100))
+	c.AddMeterReading(MeterReading{Time: t0, Glucose: 100})
+	c.Update(packetFor(t0.Add(5*time.Minute), 100))
+	c.AddMeterReading(MeterReading{Time: t0.Add(5 * time.Minute), Glucose: 105})
+	slope, _, err := c.Parameters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slope < minSlope || slope > maxSlope {
+		t.Errorf("slope == %g, want between %d and %d", slope, minSlope, maxSlope)
+	}
+}
+
+func TestTrend(t *testing.T) {
+	c := New()
+	c.Update(packetFor(t0, 100))
+	c.AddMeterReading(MeterReading{Time: t0, Glucose: 100})
+	c.Update(packetFor(t0.Add(5*time.Minute), 200))
+	c.AddMeterReading(MeterReading{Time: t0.Add(5 * time.Minute), Glucose: 200})
+	var e *Estimate
+	for i := 2; i <= 5; i++ {
+		var err error
+		e, err = c.Update(packetFor(t0.Add(time.Duration(i)*5*time.Minute), 200+12.5*float64(i-1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if math.Abs(e.RateOfChange-2.5) > 0.01 || e.Trend != SingleUp {
+		t.Errorf("RateOfChange == %g (%v), want 2.5 (SingleUp)", e.RateOfChange, e.Trend)
+	}
+}
+
+func TestTrendFromRate(t *testing.T) {
+	cases := []struct {
+		rate  float64
+		trend Trend
+	}{
+		{3.5, DoubleUp},
+		{2.5, SingleUp},
+		{1.5, FortyFiveUp},
+		{0, Flat},
+		{-1.5, FortyFiveDown},
+		{-2.5, SingleDown},
+		{-3.5, DoubleDown},
+		{10, RateOutOfRange},
+	}
+	for _, c := range cases {
+		trend := TrendFromRate(c.rate)
+		if trend != c.trend {
+			t.Errorf("TrendFromRate(%g) == %v, want %v", c.rate, trend, c.trend)
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	c := New()
+	c.Update(packetFor(t0, 100))
+	c.AddMeterReading(MeterReading{Time: t0, Glucose: 100})
+	c.Update(packetFor(t0.Add(5*time.Minute), 200))
+	c.AddMeterReading(MeterReading{Time: t0.Add(5 * time.Minute), Glucose: 200})
+	packets := make(chan *cc2500.Packet, 10)
+	packets <- packetFor(t0.Add(10*time.Minute), 120)
+	packets <- nil
+	packets <- packetFor(t0.Add(15*time.Minute), 130)
+	close(packets)
+	var glucose []float64
+	for e := range c.Stream(packets) {
+		glucose = append(glucose, math.Round(e.Glucose))
+	}
+	if len(glucose) != 2 || glucose[0] != 120 || glucose[1] != 130 {
+		t.Errorf("Stream() == %v, want [120 130]", glucose)
+	}
+}
@@ -0,0 +1,63 @@
+package calibration
+
+// Trend represents a glucose trend arrow.
+// The values match the Dexcom and Nightscout direction codes.
+type Trend int
+
+// Trend arrows.
+const (
+	None           Trend = 0
+	DoubleUp       Trend = 1
+	SingleUp       Trend = 2
+	FortyFiveUp    Trend = 3
+	Flat           Trend = 4
+	FortyFiveDown  Trend = 5
+	SingleDown     Trend = 6
+	DoubleDown     Trend = 7
+	NotComputable  Trend = 8
+	RateOutOfRange Trend = 9
+)
+
+// TrendFromRate returns the trend arrow for the given rate of change,
+// in mg/dL per minute.
+func TrendFromRate(rate float64) Trend {
+	switch {
+	case rate > 5 || rate < -5:
+		return RateOutOfRange
+	case rate > 3:
+		return DoubleUp
+	case rate > 2:
+		return SingleUp
+	case rate > 1:
+		return FortyFiveUp
+	case rate >= -1:
+		return Flat
+	case rate >= -2:
+		return FortyFiveDown
+	case rate >= -3:
+		return SingleDown
+	default:
+		return DoubleDown
+	}
+}
+
+// String returns the trend's name, as used by Nightscout.
+func (t Trend) String() string {
+	if t < 0 || int(t) >= len(trendName) {
+		return "NONE"
+	}
+	return trendName[t]
+}
+
+var trendName = []string{
+	"NONE",
+	"DoubleUp",
+	"SingleUp",
+	"FortyFiveUp",
+	"Flat",
+	"FortyFiveDown",
+	"SingleDown",
+	"DoubleDown",
+	"NOT COMPUTABLE",
+	"RATE OUT OF RANGE",
+}
@@ -282,6 +282,7 @@ const (
 	PKTCTRL1_PQT_SHIFT               = 5
 	PKTCTRL1_CRC_AUTOFLUSH           = 1 << 3
 	PKTCTRL1_APPEND_STATUS           = 1 << 2
+	PKTCTRL1_ADR_CHK_MASK            = 3 << 0
 	PKTCTRL1_ADR_CHK_NONE            = 0 << 0
 	PKTCTRL1_ADR_CHK_NO_BROADCAST    = 1 << 0
 	PKTCTRL1_ADR_CHK_00_BROADCAST    = 2 << 0
@@ -299,12 +300,16 @@ const (
 	PKTCTRL0_PKT_FORMAT_RANDOM       = 2 << 4
 	PKTCTRL0_PKT_FORMAT_ASYNC_SERIAL = 3 << 4
 	PKTCTRL0_CRC_EN                  = 1 << 2
+	PKTCTRL0_LENGTH_CONFIG_MASK      = 3 << 0
 	PKTCTRL0_LENGTH_CONFIG_FIXED     = 0 << 0
 	PKTCTRL0_LENGTH_CONFIG_VARIABLE  = 1 << 0
 	PKTCTRL0_LENGTH_CONFIG_INFINITE  = 2 << 0
 
+	MDMCFG4_CHANBW_E_MASK  = 3 << 6
 	MDMCFG4_CHANBW_E_SHIFT = 6
+	MDMCFG4_CHANBW_M_MASK  = 3 << 4
 	MDMCFG4_CHANBW_M_SHIFT = 4
+	MDMCFG4_DRATE_E_MASK   = 0xF << 0
 	MDMCFG4_DRATE_E_SHIFT  = 0
 
 	MDMCFG3_DRATE_M_SHIFT = 0
@@ -318,6 +323,8 @@ const (
 	MDMCFG2_MOD_FORMAT_ASK_OOK = 3 << 4
 	MDMCFG2_MOD_FORMAT_MSK     = 7 << 4
 
+	MDMCFG2_MANCHESTER_EN = 1 << 3
+
 	MDMCFG2_SYNC_MODE_MASK        = 7 << 0
 	MDMCFG2_SYNC_MODE_NONE        = 0 << 0
 	MDMCFG2_SYNC_MODE_15_16       = 1 << 0
@@ -346,7 +353,9 @@ const (
 
 	MDMCFG0_CHANSPC_M_SHIFT = 0
 
+	DEVIATN_DEVIATION_E_MASK  = 7 << 4
 	DEVIATN_DEVIATION_E_SHIFT = 4
+	DEVIATN_DEVIATION_M_MASK  = 7 << 0
 	DEVIATN_DEVIATION_M_SHIFT = 0
 
 	MCSM2_RX_TIME_RSSI          = 1 << 4
@@ -359,6 +368,8 @@ const (
 	MCSM1_CCA_MODE_RSSI_BELOW                  = 1 << 4
 	MCSM1_CCA_MODE_UNLESS_RECEIVING            = 2 << 4
 	MCSM1_CCA_MODE_RSSI_BELOW_UNLESS_RECEIVING = 3 << 4
+	MCSM1_CCA_MODE_MASK                        = 3 << 4
+	MCSM1_CCA_MODE_SHIFT                       = 4
 	MCSM1_RXOFF_MODE_IDLE                      = 0 << 2
 	MCSM1_RXOFF_MODE_FSTXON                    = 1 << 2
 	MCSM1_RXOFF_MODE_TX                        = 2 << 2
@@ -372,6 +383,7 @@ const (
 	MCSM0_FS_AUTOCAL_FROM_IDLE       = 1 << 4
 	MCSM0_FS_AUTOCAL_TO_IDLE         = 2 << 4
 	MCSM0_FS_AUTOCAL_TO_IDLE_EVERY_4 = 3 << 4
+	MCSM0_FS_AUTOCAL_MASK            = 3 << 4
 	MCSM0_PO_TIMEOUT_SHIFT           = 2
 	MCSM0_PO_TIMEOUT_MASK            = 3 << 2
 	MCSM0_PIN_CTRL_EN                = 1 << 1
@@ -449,6 +461,9 @@ const (
 	AGCCTRL1_CARRIER_SENSE_ABS_THR_3DB_BELOW = 0xD << 0
 	AGCCTRL1_CARRIER_SENSE_ABS_THR_2DB_BELOW = 0xE << 0
 	AGCCTRL1_CARRIER_SENSE_ABS_THR_1DB_BELOW = 0xF << 0
+	AGCCTRL1_CARRIER_SENSE_REL_THR_MASK      = 3 << 4
+	AGCCTRL1_CARRIER_SENSE_REL_THR_SHIFT     = 4
+	AGCCTRL1_CARRIER_SENSE_ABS_THR_MASK      = 0xF << 0
 
 	AGCCTRL0_HYST_LEVEL_NONE          = 0 << 6
 	AGCCTRL0_HYST_LEVEL_LOW           = 1 << 6
@@ -467,6 +482,13 @@ const (
 	AGCCTRL0_FILTER_LENGTH_32         = 2 << 0
 	AGCCTRL0_FILTER_LENGTH_64         = 3 << 0
 
+	WORCTRL_RC_PD         = 1 << 7
+	WORCTRL_EVENT1_MASK   = 7 << 4
+	WORCTRL_EVENT1_SHIFT  = 4
+	WORCTRL_RC_CAL        = 1 << 3
+	WORCTRL_WOR_RES_MASK  = 3 << 0
+	WORCTRL_WOR_RES_SHIFT = 0
+
 	FREND1_LNA_CURRENT_SHIFT          = 6
 	FREND1_LNA2MIX_CURRENT_SHIFT      = 4
 	FREND1_LODIV_BUF_CURRENT_RX_SHIFT = 2
@@ -1,14 +1,50 @@
 package cc2500
 
 import (
+	"bytes"
 	"testing"
-	"unsafe"
 )
 
 func TestRFConfiguration(t *testing.T) {
-	have := int(unsafe.Sizeof(RFConfiguration{}))
-	want := TEST0 - IOCFG2 + 1
-	if have != want {
-		t.Errorf("Sizeof(RFConfiguration) == %d, want %d", have, want)
+	regs := make([]byte, TEST0-IOCFG2+1)
+	for i := range regs {
+		regs[i] = byte(i + 1)
+	}
+	var config RFConfiguration
+	err := config.UnmarshalBinary(regs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.IOCFG2 != IOCFG2+1 || config.MDMCFG2 != MDMCFG2+1 || config.TEST0 != TEST0+1 {
+		t.Errorf("UnmarshalBinary() stored registers out of order: %+v", config)
+	}
+	data, err := config.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, regs) {
+		t.Errorf("MarshalBinary() == % X, want % X", data, regs)
+	}
+	data[0] = 0xFF
+	if config.IOCFG2 == 0xFF {
+		t.Errorf("MarshalBinary() result aliases the configuration")
+	}
+	for _, n := range []int{0, len(regs) - 1, len(regs) + 1} {
+		err = config.UnmarshalBinary(make([]byte, n))
+		if _, ok := err.(ConfigurationLengthError); !ok {
+			t.Errorf("UnmarshalBinary() of %d bytes returned %v, want ConfigurationLengthError", n, err)
+		}
+	}
+}
+
+func TestReadConfiguration(t *testing.T) {
+	r, _ := openSimulator(t)
+	config := r.ReadConfiguration()
+	if r.Error() != nil {
+		t.Fatal(r.Error())
+	}
+	want, _ := G4Profile.Configuration()
+	if *config != want {
+		t.Errorf("ReadConfiguration() == % X, want % X", config.Bytes(), want.Bytes())
 	}
 }
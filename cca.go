@@ -0,0 +1,173 @@
+package cc2500
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+)
+
+// CCAMode selects the clear channel indication that must hold
+// for the radio to go from RX to TX (MCSM1.CCA_MODE).
+type CCAMode byte
+
+// Clear channel assessment modes.
+const (
+	CCAAlways                   CCAMode = MCSM1_CCA_MODE_ALWAYS >> MCSM1_CCA_MODE_SHIFT
+	CCARSSIBelow                CCAMode = MCSM1_CCA_MODE_RSSI_BELOW >> MCSM1_CCA_MODE_SHIFT
+	CCAUnlessReceiving          CCAMode = MCSM1_CCA_MODE_UNLESS_RECEIVING >> MCSM1_CCA_MODE_SHIFT
+	CCARSSIBelowUnlessReceiving CCAMode = MCSM1_CCA_MODE_RSSI_BELOW_UNLESS_RECEIVING >> MCSM1_CCA_MODE_SHIFT
+)
+
+func (m CCAMode) String() string {
+	if int(m) >= len(ccaMode) {
+		return fmt.Sprintf("CCAMode(%d)", m)
+	}
+	return ccaMode[m]
+}
+
+var ccaMode = []string{
+	"always",
+	"RSSI below threshold",
+	"unless receiving",
+	"RSSI below threshold unless receiving",
+}
+
+// CarrierSenseDisabled disables the absolute carrier sense threshold.
+const CarrierSenseDisabled = -8
+
+// RelativeThreshold is a relative carrier sense threshold
+// (AGCCTRL1.CARRIER_SENSE_REL_THR): carrier sense is asserted
+// when the RSSI increases suddenly by the given amount.
+type RelativeThreshold byte
+
+// Relative carrier sense thresholds.
+const (
+	RelativeThresholdDisabled RelativeThreshold = iota
+	RelativeThreshold6dB
+	RelativeThreshold10dB
+	RelativeThreshold14dB
+)
+
+// ErrChannelBusy indicates that SendListenBeforeTalk
+// did not find the channel clear in any of its attempts.
+var ErrChannelBusy = errors.New("channel busy")
+
+// ReadCCAMode returns the radio's clear channel assessment mode.
+func (r *Radio) ReadCCAMode() CCAMode {
+	return CCAMode((r.hw.ReadRegister(MCSM1) & MCSM1_CCA_MODE_MASK) >> MCSM1_CCA_MODE_SHIFT)
+}
+
+// SetCCAMode sets the radio's clear channel assessment mode.
+func (r *Radio) SetCCAMode(mode CCAMode) {
+	m1 := r.hw.ReadRegister(MCSM1) &^ MCSM1_CCA_MODE_MASK
+	r.hw.WriteRegister(MCSM1, m1|byte(mode)<<MCSM1_CCA_MODE_SHIFT&MCSM1_CCA_MODE_MASK)
+}
+
+// SetCarrierSense sets the carrier sense thresholds.
+// The absolute threshold is in dB relative to the AGC's MAGN_TARGET
+// setting (-7 to 7), or CarrierSenseDisabled.
+func (r *Radio) SetCarrierSense(absolute int, relative RelativeThreshold) {
+	v, err := carrierSenseRegister(absolute, relative)
+	if err != nil {
+		r.SetError(err)
+		return
+	}
+	a1 := r.hw.ReadRegister(AGCCTRL1) &^ (AGCCTRL1_CARRIER_SENSE_REL_THR_MASK | AGCCTRL1_CARRIER_SENSE_ABS_THR_MASK)
+	r.hw.WriteRegister(AGCCTRL1, a1|v)
+}
+
+func carrierSenseRegister(absolute int, relative RelativeThreshold) (byte, error) {
+	if absolute < CarrierSenseDisabled || absolute > 7 {
+		return 0, fmt.Errorf("absolute carrier sense threshold %d dB is out of range", absolute)
+	}
+	if relative > RelativeThreshold14dB {
+		return 0, fmt.Errorf("invalid relative carrier sense threshold %d", relative)
+	}
+	return byte(relative)<<AGCCTRL1_CARRIER_SENSE_REL_THR_SHIFT | byte(absolute)&AGCCTRL1_CARRIER_SENSE_ABS_THR_MASK, nil
+}
+
+// LBTOptions controls listen-before-talk transmission.
+// Zero-valued fields are replaced by their defaults.
+type LBTOptions struct {
+	Attempts   int           // maximum number of attempts to transmit
+	MinBackoff time.Duration // initial upper bound of the random backoff
+	MaxBackoff time.Duration // limit for the doubling upper bound
+}
+
+const (
+	defaultLBTAttempts   = 5
+	defaultLBTMinBackoff = 2 * time.Millisecond
+	defaultLBTMaxBackoff = 50 * time.Millisecond
+
+	// Time for the RSSI to become valid after entering RX.
+	rssiSettle = time.Millisecond
+)
+
+func (opts *LBTOptions) setDefaults() {
+	if opts.Attempts <= 0 {
+		opts.Attempts = defaultLBTAttempts
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = defaultLBTMinBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = defaultLBTMaxBackoff
+	}
+}
+
+// SendListenBeforeTalk transmits the given packet when the channel is clear
+// according to the CCA mode. Before each attempt the radio listens
+// until the RSSI is valid; if the channel is busy, it waits for a random
+// backoff whose upper bound doubles with each attempt. An attempt in which
+// the radio has left RX before the assessment (because it received
+// a packet, for example) counts as busy. If every attempt finds
+// the channel busy, the error state is set to ErrChannelBusy.
+// Options may be nil.
+func (r *Radio) SendListenBeforeTalk(data []byte, opts *LBTOptions) {
+	var o LBTOptions
+	if opts != nil {
+		o = *opts
+	}
+	o.setDefaults()
+	t := r.txPacket(data)
+	if t == nil {
+		return
+	}
+	defer r.Strobe(SIDLE)
+	defer t.close()
+	t.fill(fifoSize)
+	backoff := o.MinBackoff
+	for i := 0; i < o.Attempts && r.Error() == nil; i++ {
+		if i != 0 {
+			time.Sleep(time.Duration(rand.Int63n(int64(backoff))))
+			backoff *= 2
+			if backoff > o.MaxBackoff {
+				backoff = o.MaxBackoff
+			}
+		}
+		r.Strobe(SRX)
+		time.Sleep(rssiSettle)
+		// Only assess the channel if the radio is still listening;
+		// STX from IDLE (after a packet was received, for example)
+		// would transmit unconditionally.
+		if r.ReadState() == STATE_RX {
+			r.Strobe(STX)
+			switch r.ReadState() {
+			case STATE_TX, STATE_FSTXON, STATE_CALIBRATE:
+				t.stream()
+				return
+			}
+		}
+		if verbose {
+			log.Printf("channel busy on attempt %d", i+1)
+		}
+		r.Strobe(SIDLE)
+		r.Strobe(SFRX)
+	}
+	r.Strobe(SFTX)
+	if r.Error() == nil {
+		r.SetError(ErrChannelBusy)
+	}
+}
@@ -0,0 +1,177 @@
+package cc2500
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestSetCCAMode(t *testing.T) {
+	r, _ := openSimulator(t)
+	for _, mode := range []CCAMode{CCARSSIBelowUnlessReceiving, CCAUnlessReceiving, CCARSSIBelow, CCAAlways} {
+		r.SetCCAMode(mode)
+		if got := r.ReadCCAMode(); got != mode {
+			t.Errorf("ReadCCAMode() == %v, want %v", got, mode)
+		}
+	}
+	if r.hw.ReadRegister(MCSM1)&^MCSM1_CCA_MODE_MASK != MCSM1_RXOFF_MODE_IDLE|MCSM1_TXOFF_MODE_IDLE {
+		t.Errorf("SetCCAMode() changed other MCSM1 fields")
+	}
+}
+
+func TestSetCarrierSense(t *testing.T) {
+	cases := []struct {
+		absolute int
+		relative RelativeThreshold
+		value    byte
+	}{
+		{0, RelativeThresholdDisabled, AGCCTRL1_CARRIER_SENSE_ABS_THR_0DB},
+		{7, RelativeThreshold6dB, AGCCTRL1_CARRIER_SENSE_REL_THR_6DB | AGCCTRL1_CARRIER_SENSE_ABS_THR_7DB_ABOVE},
+		{-3, RelativeThreshold14dB, AGCCTRL1_CARRIER_SENSE_REL_THR_14DB | AGCCTRL1_CARRIER_SENSE_ABS_THR_3DB_BELOW},
+		{CarrierSenseDisabled, RelativeThreshold10dB, AGCCTRL1_CARRIER_SENSE_REL_THR_10DB | AGCCTRL1_CARRIER_SENSE_ABS_THR_DISABLE},
+	}
+	r, _ := openSimulator(t)
+	for _, c := range cases {
+		r.SetCarrierSense(c.absolute, c.relative)
+		if r.Error() != nil {
+			t.Fatal(r.Error())
+		}
+		want := AGCCTRL1_AGC_LNA_PRIORITY_0 | c.value
+		if got := r.hw.ReadRegister(AGCCTRL1); got != want {
+			t.Errorf("SetCarrierSense(%d, %d) AGCCTRL1 == %02X, want %02X", c.absolute, c.relative, got, want)
+		}
+	}
+	r.SetCarrierSense(8, RelativeThresholdDisabled)
+	if r.Error() == nil {
+		t.Errorf("SetCarrierSense(8, ...) succeeded")
+	}
+}
+
+func openLBT(t *testing.T) (*Radio, *Simulator) {
+	r, s := openSimulator(t)
+	r.SetCCAMode(CCARSSIBelowUnlessReceiving)
+	r.SetCarrierSense(0, RelativeThresholdDisabled)
+	return r, s
+}
+
+func TestSendListenBeforeTalk(t *testing.T) {
+	r, s := openLBT(t)
+	r.SendListenBeforeTalk(p2, nil)
+	if r.Error() != nil {
+		t.Fatal(r.Error())
+	}
+	sent := s.Transmitted()
+	if len(sent) != 1 || !bytes.Equal(sent[0], p2) {
+		t.Errorf("Transmitted() == % X, want [% X]", sent, p2)
+	}
+}
+
+func TestSendListenBeforeTalkBusy(t *testing.T) {
+	r, s := openLBT(t)
+	s.SetChannelBusy(true)
+	r.SendListenBeforeTalk(p2, &LBTOptions{Attempts: 3, MinBackoff: time.Millisecond})
+	if r.Error() != ErrChannelBusy {
+		t.Errorf("SendListenBeforeTalk() error == %v, want %v", r.Error(), ErrChannelBusy)
+	}
+	r.SetError(nil)
+	if sent := s.Transmitted(); len(sent) != 0 {
+		t.Errorf("Transmitted() == % X on busy channel", sent)
+	}
+	if n := r.ReadNumTXBytes(); n != 0 {
+		t.Errorf("%d bytes left in TX FIFO", n)
+	}
+	// CCA is not applied when the mode is "always".
+	r.SetCCAMode(CCAAlways)
+	r.SendListenBeforeTalk(p2, nil)
+	if r.Error() != nil {
+		t.Fatal(r.Error())
+	}
+	if sent := s.Transmitted(); len(sent) != 1 {
+		t.Errorf("Transmitted() == % X, want 1 packet", sent)
+	}
+}
+
+func TestSendListenBeforeTalkRetry(t *testing.T) {
+	r, s := openLBT(t)
+	s.SetChannelBusy(true)
+	time.AfterFunc(5*time.Millisecond, func() { s.SetChannelBusy(false) })
+	r.SendListenBeforeTalk(p2, &LBTOptions{Attempts: 10, MinBackoff: 2 * time.Millisecond})
+	if r.Error() != nil {
+		t.Fatal(r.Error())
+	}
+	if sent := s.Transmitted(); len(sent) != 1 || !bytes.Equal(sent[0], p2) {
+		t.Errorf("Transmitted() == % X, want [% X]", sent, p2)
+	}
+}
+
+func TestProfileCCA(t *testing.T) {
+	p := G4Profile
+	p.CCAMode = CCARSSIBelow
+	p.CarrierSense = -2
+	p.RelativeCarrierSense = RelativeThreshold10dB
+	rf, err := p.Configuration()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rf.MCSM1&MCSM1_CCA_MODE_MASK != MCSM1_CCA_MODE_RSSI_BELOW {
+		t.Errorf("MCSM1 == %02X", rf.MCSM1)
+	}
+	if rf.AGCCTRL1 != AGCCTRL1_CARRIER_SENSE_REL_THR_10DB|AGCCTRL1_CARRIER_SENSE_ABS_THR_2DB_BELOW {
+		t.Errorf("AGCCTRL1 == %02X", rf.AGCCTRL1)
+	}
+	p.CarrierSense = 9
+	if _, err := p.Configuration(); err == nil {
+		t.Errorf("Configuration() with carrier sense threshold 9 succeeded")
+	}
+}
+
+// deafHardware ignores SRX, as if a packet had just ended RX.
+type deafHardware struct {
+	*Simulator
+}
+
+func (h deafHardware) Strobe(cmd byte) (byte, error) {
+	if cmd == SRX {
+		cmd = SNOP
+	}
+	return h.Simulator.Strobe(cmd)
+}
+
+func TestSendListenBeforeTalkNotListening(t *testing.T) {
+	s := NewSimulator()
+	r := OpenHardware(deafHardware{s})
+	r.Init(BaseFrequency)
+	r.SetCCAMode(CCARSSIBelowUnlessReceiving)
+	r.SendListenBeforeTalk(p2, &LBTOptions{Attempts: 2, MinBackoff: time.Millisecond})
+	if r.Error() != ErrChannelBusy {
+		t.Errorf("SendListenBeforeTalk() error == %v, want %v", r.Error(), ErrChannelBusy)
+	}
+	if sent := s.Transmitted(); len(sent) != 0 {
+		t.Errorf("Transmitted() == % X without clear channel assessment", sent)
+	}
+}
+
+// stuckHardware never leaves TX.
+type stuckHardware struct {
+	*Simulator
+}
+
+func (h stuckHardware) ReadRegister(addr byte) byte {
+	if addr == MARCSTATE {
+		return MARCSTATE_TX
+	}
+	return h.Simulator.ReadRegister(addr)
+}
+
+func TestAwaitTXTimeout(t *testing.T) {
+	r := OpenHardware(stuckHardware{NewSimulator()})
+	r.Init(BaseFrequency)
+	start := time.Now()
+	r.Send(p2)
+	if r.Error() != ErrTransmitTimeout {
+		t.Errorf("Send() error == %v, want %v", r.Error(), ErrTransmitTimeout)
+	}
+	if elapsed := time.Since(start); elapsed > r.txFIFOTime()+100*time.Millisecond {
+		t.Errorf("Send() returned after %v", elapsed)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+// Act as a Dexcom G4 transmitter, sending a reading on each channel
+// every 5 minutes, for testing receivers without a real sensor.
+
+import (
+	"flag"
+	"log"
+	"time"
+
+	"github.com/ecc1/cc2500"
+)
+
+var (
+	transmitterID = flag.String("id", "6GN7J", "transmitter `ID`")
+	raw           = flag.Uint("raw", 150000, "initial raw `value`")
+	filtered      = flag.Uint("filtered", 150000, "initial filtered `value`")
+	step          = flag.Int("step", 0, "change in raw and filtered values per reading")
+	battery       = flag.Uint("battery", 215, "battery `level`")
+	count         = flag.Int("n", 0, "send only `count` readings")
+	interval      = flag.Duration("interval", 5*time.Minute, "reading interval")
+)
+
+func main() {
+	log.SetFlags(log.Ltime | log.Lmicroseconds | log.LUTC)
+	config := cc2500.DefaultConfig()
+	err := config.ApplyEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	config.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	err = cc2500.ValidateTransmitterID(*transmitterID)
+	if err != nil {
+		log.Fatal(err)
+	}
+	r := cc2500.OpenWithConfig(config)
+	if r.Error() != nil {
+		log.Fatal(r.Error())
+	}
+	err = transmit(r)
+	r.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// transmit sends readings until the count is reached or an error occurs.
+func transmit(r *cc2500.Radio) error {
+	r.Init(cc2500.BaseFrequency)
+	opts := cc2500.DefaultReceiverOptions()
+	opts.ReadingInterval = *interval
+	p := &cc2500.Packet{
+		TransmitterID: *transmitterID,
+		Raw:           uint32(*raw),
+		Filtered:      uint32(*filtered),
+		Battery:       uint8(*battery),
+	}
+	start := time.Now()
+	for n := 0; r.Error() == nil; n++ {
+		if *count != 0 && n == *count {
+			return nil
+		}
+		log.Printf("sending reading %d: raw = %d, filtered = %d", p.Sequence, p.Raw, p.Filtered)
+		r.SendReading(p, &opts)
+		p.Sequence++
+		p.Raw = uint32(int(p.Raw) + *step)
+		p.Filtered = uint32(int(p.Filtered) + *step)
+		time.Sleep(time.Until(start.Add(time.Duration(n+1) * opts.ReadingInterval)))
+	}
+	return r.Error()
+}
@@ -0,0 +1,70 @@
+// The g4server command receives Dexcom G4 readings and makes them
+// available to other programs over HTTP, so that several clients
+// can share a single radio.
+//
+// Endpoints:
+//
+//	/readings?since=T  JSON array of stored readings received after T
+//	/status            JSON receiver status
+//	/stream            each new reading as a Server-Sent Event
+//	/metrics           reception statistics in Prometheus text format
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/ecc1/cc2500"
+)
+
+func main() {
+	config := cc2500.DefaultConfig()
+	err := config.ApplyEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	addr := flag.String("addr", "localhost:8025", "listen on `address`")
+	size := flag.Int("n", 288, "keep the most recent `n` readings")
+	stateFile := flag.String("state", "", "save and restore frequency offsets in `file`")
+	wor := flag.Bool("wor", false, "let the radio sleep in Wake-on-Radio mode between readings")
+	sleep := flag.Bool("sleep", false, "put the radio to sleep between readings")
+	config.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if *size <= 0 {
+		log.Fatal("number of readings must be positive")
+	}
+	r := cc2500.OpenWithConfig(config)
+	if r.Error() != nil {
+		log.Fatal(r.Error())
+	}
+	log.Printf("connected to %s radio on %s", r.Name(), r.Device())
+	s := NewServer(*size, func() Status {
+		return Status{
+			Radio:          r.Name(),
+			Device:         r.Device(),
+			ReceiverStatus: r.ReceiverStatus(),
+		}
+	})
+	opts := cc2500.DefaultReceiverOptions()
+	opts.Metrics = cc2500.NewMetrics()
+	opts.StateFile = *stateFile
+	opts.WakeOnRadio = *wor
+	opts.Sleep = *sleep
+	readings := r.ReceiveReadings(&opts)
+	go func() {
+		for p := range readings {
+			if p != nil {
+				s.Add(p)
+			}
+		}
+		log.Fatal(r.Error())
+	}()
+	mux := http.NewServeMux()
+	mux.Handle("/", s.Handler())
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		_, _ = opts.Metrics.WriteTo(w)
+	})
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
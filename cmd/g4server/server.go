@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ecc1/cc2500"
+)
+
+// Server keeps the most recent readings in a ring buffer
+// and serves them over HTTP.
+type Server struct {
+	mu      sync.Mutex
+	ring    []*cc2500.Packet
+	next    int // index of the slot for the next reading
+	full    bool
+	clients map[chan *cc2500.Packet]struct{}
+	status  func() Status
+}
+
+// Status is the response to a /status request.
+type Status struct {
+	Radio  string
+	Device string
+	cc2500.ReceiverStatus
+}
+
+// NewServer returns a server that keeps up to size readings
+// and calls status to answer /status requests.
+func NewServer(size int, status func() Status) *Server {
+	return &Server{
+		ring:    make([]*cc2500.Packet, size),
+		clients: make(map[chan *cc2500.Packet]struct{}),
+		status:  status,
+	}
+}
+
+// Add stores a reading and delivers it to stream clients.
+// Clients that are not keeping up miss the reading.
+func (s *Server) Add(p *cc2500.Packet) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ring[s.next] = p
+	s.next++
+	if s.next == len(s.ring) {
+		s.next = 0
+		s.full = true
+	}
+	for c := range s.clients {
+		select {
+		case c <- p:
+		default:
+		}
+	}
+}
+
+// Readings returns the stored readings received after the given time,
+// oldest first.
+func (s *Server) Readings(since time.Time) []*cc2500.Packet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var v []*cc2500.Packet
+	start := 0
+	if s.full {
+		start = s.next
+	}
+	n := s.next
+	if s.full {
+		n = len(s.ring)
+	}
+	for i := 0; i < n; i++ {
+		p := s.ring[(start+i)%len(s.ring)]
+		if p.Timestamp.After(since) {
+			v = append(v, p)
+		}
+	}
+	return v
+}
+
+func (s *Server) subscribe() chan *cc2500.Packet {
+	c := make(chan *cc2500.Packet, 16)
+	s.mu.Lock()
+	s.clients[c] = struct{}{}
+	s.mu.Unlock()
+	return c
+}
+
+func (s *Server) unsubscribe(c chan *cc2500.Packet) {
+	s.mu.Lock()
+	delete(s.clients, c)
+	s.mu.Unlock()
+}
+
+// Handler returns the HTTP handler for the server's endpoints.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/readings", s.serveReadings)
+	mux.HandleFunc("/status", s.serveStatus)
+	mux.HandleFunc("/stream", s.serveStream)
+	return mux
+}
+
+// The since parameter is either an RFC 3339 time
+// or a number of milliseconds since the Unix epoch.
+func parseSince(since string) (time.Time, error) {
+	if since == "" {
+		return time.Time{}, nil
+	}
+	ms, err := strconv.ParseInt(since, 10, 64)
+	if err == nil {
+		return time.Unix(0, ms*int64(time.Millisecond)), nil
+	}
+	return time.Parse(time.RFC3339, since)
+}
+
+func (s *Server) serveReadings(w http.ResponseWriter, req *http.Request) {
+	since, err := parseSince(req.FormValue("since"))
+	if err != nil {
+		http.Error(w, "invalid since parameter", http.StatusBadRequest)
+		return
+	}
+	readings := s.Readings(since)
+	if readings == nil {
+		readings = []*cc2500.Packet{}
+	}
+	writeJSON(w, readings)
+}
+
+func (s *Server) serveStatus(w http.ResponseWriter, req *http.Request) {
+	writeJSON(w, s.status())
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// serveStream sends each new reading as a Server-Sent Event.
+func (s *Server) serveStream(w http.ResponseWriter, req *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	c := s.subscribe()
+	defer s.unsubscribe(c)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case p := <-c:
+			data, err := json.Marshal(p)
+			if err != nil {
+				continue
+			}
+			_, err = fmt.Fprintf(w, "event: reading\ndata: %s\n\n", data)
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-req.Context().Done():
+			return
+		}
+	}
+}
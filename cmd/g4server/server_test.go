@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ecc1/cc2500"
+)
+
+var t0 = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func testReading(i int) *cc2500.Packet {
+	return &cc2500.Packet{
+		Timestamp:     t0.Add(time.Duration(i) * 5 * time.Minute),
+		TransmitterID: "6GN7J",
+		Raw:           uint32(150000 + i),
+		RSSI:          -60,
+	}
+}
+
+func testServer() *Server {
+	return NewServer(3, func() Status {
+		return Status{
+			Radio:  "CC2500",
+			Device: "simulator",
+			ReceiverStatus: cc2500.ReceiverStatus{
+				Running:  true,
+				State:    "RX",
+				LastRSSI: -65,
+				Transmitters: []cc2500.TransmitterStatus{
+					{TransmitterID: "6GN7J", InSync: true, Offsets: []uint8{0xFD, 0xFD, 0xFE, 0xFC}},
+				},
+			},
+		}
+	})
+}
+
+func TestRingBuffer(t *testing.T) {
+	s := testServer()
+	for i := 0; i < 5; i++ {
+		s.Add(testReading(i))
+	}
+	v := s.Readings(time.Time{})
+	if len(v) != 3 || v[0].Raw != 150002 || v[2].Raw != 150004 {
+		t.Errorf("Readings() == %v, want readings 2 through 4", v)
+	}
+	v = s.Readings(t0.Add(15 * time.Minute))
+	if len(v) != 1 || v[0].Raw != 150004 {
+		t.Errorf("Readings(since) == %v, want reading 4", v)
+	}
+}
+
+func getJSON(t *testing.T, url string, v interface{}) {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s: %s", url, resp.Status)
+	}
+	err = json.NewDecoder(resp.Body).Decode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadingsEndpoint(t *testing.T) {
+	s := testServer()
+	s.Add(testReading(0))
+	s.Add(testReading(1))
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+	var v []cc2500.Packet
+	getJSON(t, server.URL+"/readings?since="+t0.Format(time.RFC3339), &v)
+	if len(v) != 1 || v[0].Raw != 150001 {
+		t.Errorf("/readings == %v, want reading 1", v)
+	}
+	ms := t0.Add(-time.Minute).UnixNano() / int64(time.Millisecond)
+	getJSON(t, server.URL+"/readings?since="+strconv.FormatInt(ms, 10), &v)
+	if len(v) != 2 {
+		t.Errorf("/readings with milliseconds returned %d readings, want 2", len(v))
+	}
+	resp, err := http.Get(server.URL + "/readings?since=yesterday")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("/readings with invalid since: %s", resp.Status)
+	}
+}
+
+func TestStatusEndpoint(t *testing.T) {
+	server := httptest.NewServer(testServer().Handler())
+	defer server.Close()
+	var status Status
+	getJSON(t, server.URL+"/status", &status)
+	if status.Device != "simulator" || status.State != "RX" || status.LastRSSI != -65 {
+		t.Errorf("/status == %+v", status)
+	}
+	if len(status.Transmitters) != 1 || status.Transmitters[0].Offsets[2] != 0xFE {
+		t.Errorf("/status transmitters == %+v", status.Transmitters)
+	}
+}
+
+func TestStreamEndpoint(t *testing.T) {
+	s := testServer()
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Errorf("Content-Type == %q", resp.Header.Get("Content-Type"))
+	}
+	// The handler has subscribed once the headers have been received.
+	s.Add(testReading(7))
+	lines := bufio.NewScanner(resp.Body)
+	for lines.Scan() {
+		line := lines.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var p cc2500.Packet
+		err = json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Raw != 150007 {
+			t.Errorf("streamed reading == %+v, want reading 7", p)
+		}
+		return
+	}
+	t.Fatalf("stream ended: %v", lines.Err())
+}
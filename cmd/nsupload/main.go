@@ -0,0 +1,115 @@
+// The nsupload command receives Dexcom G4 readings
+// and uploads them to a Nightscout site.
+// Entries that cannot be uploaded are queued on disk
+// and retried until the site can be reached.
+// Meter readings in the -cal file calibrate the sensor; with -log,
+// readings recorded earlier (by scantest -log, for example) are used
+// to pair meter readings entered before the uploader started.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/ecc1/cc2500"
+	"github.com/ecc1/cc2500/calibration"
+	"github.com/ecc1/cc2500/readinglog"
+)
+
+const retryInterval = time.Minute
+
+func main() {
+	config := cc2500.DefaultConfig()
+	err := config.ApplyEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	url := flag.String("url", os.Getenv("NIGHTSCOUT_SITE"), "Nightscout base `URL`")
+	secret := flag.String("secret", os.Getenv("NIGHTSCOUT_API_SECRET"), "Nightscout API `secret`")
+	queueFile := flag.String("queue", "nsupload-queue.jsonl", "queue entries awaiting upload in `file`")
+	calFile := flag.String("cal", "", "read meter readings from JSON `file` (reread when it changes)")
+	logDir := flag.String("log", "", "pair meter readings with past readings from the reading log in `directory`")
+	stateFile := flag.String("state", "", "save and restore frequency offsets in `file`")
+	config.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if *url == "" {
+		log.Fatal("Nightscout URL must be specified with -url or NIGHTSCOUT_SITE")
+	}
+	cal := calibration.New()
+	var meterFile *MeterFile
+	if *calFile != "" {
+		meterFile = NewMeterFile(*calFile)
+		meter, err := meterFile.Read()
+		if err != nil {
+			log.Fatal(err)
+		}
+		var packets []*cc2500.Packet
+		if *logDir != "" {
+			packets, err = readinglog.ReadDir(*logDir)
+			if _, ok := err.(readinglog.MalformedLinesError); ok {
+				log.Print(err)
+			} else if err != nil {
+				log.Fatal(err)
+			}
+		}
+		seedCalibration(cal, packets, meter)
+	}
+	client := NewClient(*url, *secret)
+	queue := NewQueue(*queueFile)
+	r := cc2500.OpenWithConfig(config)
+	if r.Error() != nil {
+		log.Fatal(r.Error())
+	}
+	log.Printf("connected to %s radio on %s", r.Name(), r.Device())
+	opts := cc2500.DefaultReceiverOptions()
+	opts.StateFile = *stateFile
+	readings := r.ReceiveReadings(&opts)
+	retry := time.Tick(retryInterval)
+	for {
+		select {
+		case p, ok := <-readings:
+			if !ok {
+				flush(queue, client)
+				if r.Error() != nil {
+					log.Fatalf("receiver stopped: %v", r.Error())
+				}
+				log.Fatal("receiver stopped")
+			}
+			if p == nil {
+				continue
+			}
+			e, err := cal.Update(p)
+			if err != nil {
+				e = nil
+			}
+			logUnpaired(cal)
+			err = queue.Add(NewEntry(p, e))
+			if err != nil {
+				log.Print(err)
+				continue
+			}
+			flush(queue, client)
+		case <-retry:
+			if meterFile != nil {
+				meter, err := meterFile.Read()
+				if err != nil {
+					log.Print(err)
+				}
+				addMeterReadings(cal, meter)
+			}
+			flush(queue, client)
+		}
+	}
+}
+
+func flush(queue *Queue, client *Client) {
+	n, err := queue.Flush(client)
+	if n != 0 {
+		log.Printf("uploaded %d entries", n)
+	}
+	if err != nil {
+		log.Print(err)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/ecc1/cc2500"
+	"github.com/ecc1/cc2500/calibration"
+)
+
+// MeterFile holds meter readings as a JSON array of objects
+// with Time and Glucose fields. It is read again when it changes,
+// so readings can be added while the uploader is running.
+type MeterFile struct {
+	path    string
+	modTime time.Time
+	seen    map[time.Time]bool
+}
+
+// NewMeterFile returns a MeterFile for the given path.
+func NewMeterFile(path string) *MeterFile {
+	return &MeterFile{path: path, seen: make(map[time.Time]bool)}
+}
+
+// Read returns the readings added since the last call,
+// or none if the file has not been modified.
+func (f *MeterFile) Read() ([]calibration.MeterReading, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return nil, err
+	}
+	if info.ModTime().Equal(f.modTime) {
+		return nil, nil
+	}
+	data, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return nil, err
+	}
+	var meter []calibration.MeterReading
+	err = json.Unmarshal(data, &meter)
+	if err != nil {
+		return nil, err
+	}
+	f.modTime = info.ModTime()
+	var added []calibration.MeterReading
+	for _, m := range meter {
+		if f.seen[m.Time] {
+			continue
+		}
+		f.seen[m.Time] = true
+		added = append(added, m)
+	}
+	return added, nil
+}
+
+// addMeterReadings enters meter readings, logging those that are rejected.
+func addMeterReadings(cal *calibration.Calibrator, meter []calibration.MeterReading) {
+	for _, m := range meter {
+		err := cal.AddMeterReading(m)
+		if err != nil {
+			log.Printf("meter reading at %s: %v", m.Time.Format(time.RFC3339), err)
+		}
+	}
+}
+
+// logUnpaired logs the meter readings that the calibrator could not use.
+func logUnpaired(cal *calibration.Calibrator) {
+	for _, m := range cal.Unpaired() {
+		log.Printf("meter reading at %s: no sensor reading within 10 minutes", m.Time.Format(time.RFC3339))
+	}
+}
+
+// seedCalibration replays past sensor readings and meter readings
+// in time order, so that meter readings entered before the uploader
+// started can be paired with the sensor readings around them.
+func seedCalibration(cal *calibration.Calibrator, packets []*cc2500.Packet, meter []calibration.MeterReading) {
+	meter = append([]calibration.MeterReading(nil), meter...)
+	sort.Slice(meter, func(i, j int) bool { return meter[i].Time.Before(meter[j].Time) })
+	for _, p := range packets {
+		for len(meter) != 0 && meter[0].Time.Before(p.Timestamp) {
+			addMeterReadings(cal, meter[:1])
+			meter = meter[1:]
+		}
+		_, _ = cal.Update(p)
+	}
+	addMeterReadings(cal, meter)
+	logUnpaired(cal)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ecc1/cc2500"
+	"github.com/ecc1/cc2500/calibration"
+)
+
+// Entry is a Nightscout "entries" document.
+type Entry struct {
+	Type       string `json:"type"`
+	Device     string `json:"device"`
+	Date       int64  `json:"date"` // milliseconds since the Unix epoch
+	DateString string `json:"dateString"`
+	SGV        int    `json:"sgv,omitempty"`
+	Direction  string `json:"direction,omitempty"`
+	Unfiltered uint32 `json:"unfiltered"` // raw sensor value
+	Filtered   uint32 `json:"filtered"`
+	RSSI       int    `json:"rssi"`
+	Battery    uint8  `json:"battery"`
+}
+
+// Entry types.
+const (
+	sgvType = "sgv" // calibrated sensor glucose value
+	rawType = "raw" // uncalibrated sensor values only
+)
+
+// NewEntry converts a packet and its glucose estimate (which may be nil)
+// into a Nightscout entry. Without an estimate, the entry has type "raw"
+// rather than "sgv", so it is not displayed as a glucose value.
+func NewEntry(p *cc2500.Packet, e *calibration.Estimate) Entry {
+	entry := Entry{
+		Type:       rawType,
+		Device:     "cc2500://" + p.TransmitterID,
+		Date:       p.Timestamp.UnixNano() / int64(time.Millisecond),
+		DateString: p.Timestamp.Format(time.RFC3339),
+		Unfiltered: p.Raw,
+		Filtered:   p.Filtered,
+		RSSI:       p.RSSI,
+		Battery:    p.Battery,
+	}
+	if e != nil {
+		entry.Type = sgvType
+		entry.SGV = int(math.Round(e.Glucose))
+		entry.Direction = e.Trend.String()
+	}
+	return entry
+}
+
+// Client uploads entries to a Nightscout site.
+type Client struct {
+	URL        string // base URL of the site
+	HashedKey  string // SHA1 hash of the API secret, in hex
+	HTTPClient *http.Client
+}
+
+// NewClient returns a client for the Nightscout site at the given base URL.
+func NewClient(url string, secret string) *Client {
+	return &Client{
+		URL:        strings.TrimSuffix(url, "/"),
+		HashedKey:  hashSecret(secret),
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func hashSecret(secret string) string {
+	h := sha1.Sum([]byte(secret))
+	return hex.EncodeToString(h[:])
+}
+
+// StatusError is returned when the server rejects an upload.
+type StatusError struct {
+	Status string
+	Body   string
+}
+
+func (e StatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("Nightscout upload failed: %s", e.Status)
+	}
+	return fmt.Sprintf("Nightscout upload failed: %s: %s", e.Status, e.Body)
+}
+
+// Upload posts the given entries.
+func (c *Client) Upload(entries []Entry) error {
+	body, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", c.URL+"/api/v1/entries", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("api-secret", c.HashedKey)
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode/100 != 2 {
+		return StatusError{Status: resp.Status, Body: strings.TrimSpace(string(msg))}
+	}
+	return nil
+}
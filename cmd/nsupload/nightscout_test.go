@@ -0,0 +1,295 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ecc1/cc2500"
+	"github.com/ecc1/cc2500/calibration"
+	"github.com/ecc1/cc2500/readinglog"
+)
+
+// nightscout is a stand-in for a Nightscout site.
+type nightscout struct {
+	mu      sync.Mutex
+	down    bool
+	entries []Entry
+	batches []int // number of entries in each accepted request
+	accept  int   // if nonzero, number of requests to accept before going down
+}
+
+func (ns *nightscout) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	if req.Method != "POST" || req.URL.Path != "/api/v1/entries" {
+		http.NotFound(w, req)
+		return
+	}
+	if req.Header.Get("api-secret") != hashSecret("secret") {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if ns.accept != 0 && len(ns.batches) == ns.accept {
+		ns.down = true
+	}
+	if ns.down {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	var entries []Entry
+	err := json.NewDecoder(req.Body).Decode(&entries)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ns.entries = append(ns.entries, entries...)
+	ns.batches = append(ns.batches, len(entries))
+	w.WriteHeader(http.StatusOK)
+}
+
+var testTime = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func testPacket() *cc2500.Packet {
+	return &cc2500.Packet{
+		Timestamp:     testTime,
+		TransmitterID: "6GN7J",
+		Raw:           160000,
+		Filtered:      158000,
+		Battery:       214,
+		RSSI:          -68,
+	}
+}
+
+func TestNewEntry(t *testing.T) {
+	p := testPacket()
+	e := NewEntry(p, nil)
+	want := Entry{
+		Type:       "raw",
+		Device:     "cc2500://6GN7J",
+		Date:       testTime.Unix() * 1000,
+		DateString: "2026-03-01T12:00:00Z",
+		Unfiltered: 160000,
+		Filtered:   158000,
+		RSSI:       -68,
+		Battery:    214,
+	}
+	if e != want {
+		t.Errorf("NewEntry() == %+v, want %+v", e, want)
+	}
+	e = NewEntry(p, &calibration.Estimate{Glucose: 123.6, Trend: calibration.Flat})
+	if e.Type != "sgv" || e.SGV != 124 || e.Direction != "Flat" {
+		t.Errorf("NewEntry() type == %q, sgv == %d, direction == %q, want sgv, 124, Flat", e.Type, e.SGV, e.Direction)
+	}
+}
+
+func TestUpload(t *testing.T) {
+	ns := &nightscout{}
+	server := httptest.NewServer(ns)
+	defer server.Close()
+	e := NewEntry(testPacket(), nil)
+	err := NewClient(server.URL+"/", "secret").Upload([]Entry{e})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ns.entries) != 1 || ns.entries[0] != e {
+		t.Errorf("uploaded %+v, want [%+v]", ns.entries, e)
+	}
+	err = NewClient(server.URL, "wrong").Upload([]Entry{e})
+	if _, ok := err.(StatusError); !ok {
+		t.Errorf("Upload() with wrong secret returned %v, want StatusError", err)
+	}
+}
+
+func TestQueue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nsupload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ns := &nightscout{down: true}
+	server := httptest.NewServer(ns)
+	defer server.Close()
+	client := NewClient(server.URL, "secret")
+	queue := NewQueue(filepath.Join(dir, "queue.jsonl"))
+	var want []Entry
+	for i := 0; i < 3; i++ {
+		p := testPacket()
+		p.Timestamp = p.Timestamp.Add(time.Duration(i) * 5 * time.Minute)
+		e := NewEntry(p, nil)
+		want = append(want, e)
+		err = queue.Add(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = queue.Flush(client)
+		if err == nil {
+			t.Fatal("Flush() succeeded while server is down")
+		}
+	}
+	// A new queue using the same file sees the pending entries.
+	queue = NewQueue(filepath.Join(dir, "queue.jsonl"))
+	ns.down = false
+	n, err := queue.Flush(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(want) {
+		t.Errorf("Flush() uploaded %d entries, want %d", n, len(want))
+	}
+	for i := range want {
+		if ns.entries[i] != want[i] {
+			t.Errorf("entry %d == %+v, want %+v", i, ns.entries[i], want[i])
+		}
+	}
+	pending, err := queue.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("%d entries still queued after Flush()", len(pending))
+	}
+}
+
+func TestFlushBatches(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nsupload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ns := &nightscout{accept: 2}
+	server := httptest.NewServer(ns)
+	defer server.Close()
+	client := NewClient(server.URL, "secret")
+	queue := NewQueue(filepath.Join(dir, "queue.jsonl"))
+	total := 2*uploadBatch + 50
+	for i := 0; i < total; i++ {
+		p := testPacket()
+		p.Timestamp = p.Timestamp.Add(time.Duration(i) * 5 * time.Minute)
+		err = queue.Add(NewEntry(p, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The server fails after two batches; they stay uploaded.
+	n, err := queue.Flush(client)
+	if err == nil {
+		t.Error("Flush() succeeded when the server went down")
+	}
+	if n != 2*uploadBatch {
+		t.Errorf("Flush() uploaded %d entries, want %d", n, 2*uploadBatch)
+	}
+	pending, err := queue.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 50 {
+		t.Errorf("%d entries queued after partial Flush(), want 50", len(pending))
+	}
+	ns.down, ns.accept = false, 0
+	n, err = queue.Flush(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 50 {
+		t.Errorf("Flush() uploaded %d entries, want 50", n)
+	}
+	want := []int{uploadBatch, uploadBatch, 50}
+	if !reflect.DeepEqual(ns.batches, want) {
+		t.Errorf("uploaded batches of %v entries, want %v", ns.batches, want)
+	}
+	for i, e := range ns.entries {
+		if e.Date != testTime.Add(time.Duration(i)*5*time.Minute).Unix()*1000 {
+			t.Fatalf("entry %d uploaded out of order", i)
+		}
+	}
+}
+
+func TestMeterFileCalibration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nsupload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// Two hours of readings every 5 minutes, recorded before startup,
+	// with a sensitivity of 1000 counts per mg/dL.
+	start := time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+	w, err := readinglog.Create(filepath.Join(dir, "log"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 24; i++ {
+		p := testPacket()
+		p.Timestamp = start.Add(time.Duration(i) * 5 * time.Minute)
+		p.Raw = uint32(30000 + 1000*(100+2*i))
+		p.Filtered = p.Raw
+		err = w.Append(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Close()
+	calFile := filepath.Join(dir, "meter.json")
+	meter := `[{"Time": "` + start.Add(32*time.Minute).Format(time.RFC3339) + `", "Glucose": 112},
+		{"Time": "` + start.Add(91*time.Minute).Format(time.RFC3339) + `", "Glucose": 136}]`
+	err = ioutil.WriteFile(calFile, []byte(meter), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	packets, err := readinglog.ReadDir(filepath.Join(dir, "log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mf := NewMeterFile(calFile)
+	readings, err := mf.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cal := calibration.New()
+	seedCalibration(cal, packets, readings)
+	if !cal.Calibrated() {
+		t.Fatal("not calibrated from meter file and reading log")
+	}
+	p := testPacket()
+	p.Timestamp = time.Now()
+	p.Raw, p.Filtered = 30000+1000*150, 30000+1000*150
+	e, err := cal.Update(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Glucose < 145 || e.Glucose > 155 {
+		t.Errorf("estimated glucose == %g, want about 150", e.Glucose)
+	}
+	if NewEntry(p, e).Type != "sgv" {
+		t.Errorf("calibrated entry type == %q, want sgv", NewEntry(p, e).Type)
+	}
+	// Unchanged file yields no readings; a new reading is picked up.
+	readings, err = mf.Read()
+	if err != nil || len(readings) != 0 {
+		t.Errorf("Read() of unchanged file == %v, %v", readings, err)
+	}
+	meter = meter[:len(meter)-1] + `, {"Time": "` + p.Timestamp.Format(time.RFC3339) + `", "Glucose": 150}]`
+	err = ioutil.WriteFile(calFile, []byte(meter), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	err = os.Chtimes(calFile, later, later)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readings, err = mf.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(readings) != 1 || readings[0].Glucose != 150 {
+		t.Errorf("Read() of changed file == %v, want the new reading", readings)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Maximum number of entries uploaded in one request.
+const uploadBatch = 100
+
+// Queue holds entries that have not yet been uploaded,
+// in a JSON Lines file so they survive restarts.
+type Queue struct {
+	path string
+}
+
+// NewQueue returns a queue stored in the given file.
+func NewQueue(path string) *Queue {
+	return &Queue{path: path}
+}
+
+// Add appends an entry to the queue.
+func (q *Queue) Add(e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(q.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	if err == nil {
+		err = f.Sync()
+	}
+	cerr := f.Close()
+	if err != nil {
+		return err
+	}
+	return cerr
+}
+
+// Entries returns the queued entries, oldest first.
+// Lines that cannot be decoded (such as one left incomplete by a crash)
+// are skipped.
+func (q *Queue) Entries() ([]Entry, error) {
+	f, err := os.Open(q.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Entry
+		if json.Unmarshal(scanner.Bytes(), &e) != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// Clear removes all entries from the queue.
+func (q *Queue) Clear() error {
+	err := os.Remove(q.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Replace the queued entries with the given ones.
+func (q *Queue) rewrite(entries []Entry) error {
+	if len(entries) == 0 {
+		return q.Clear()
+	}
+	f, err := ioutil.TempFile(filepath.Dir(q.path), filepath.Base(q.path)+".")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, e := range entries {
+		err = enc.Encode(e)
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	cerr := f.Close()
+	if err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), q.path)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+	}
+	return err
+}
+
+// Flush uploads the queued entries in batches of at most uploadBatch,
+// removing each batch from the queue once it has been accepted.
+// It returns the number of entries uploaded.
+func (q *Queue) Flush(c *Client) (int, error) {
+	entries, err := q.Entries()
+	if err != nil || len(entries) == 0 {
+		return 0, err
+	}
+	n := 0
+	for n < len(entries) {
+		batch := entries[n:]
+		if len(batch) > uploadBatch {
+			batch = batch[:uploadBatch]
+		}
+		err = c.Upload(batch)
+		if err != nil {
+			break
+		}
+		n += len(batch)
+	}
+	if n == 0 {
+		return 0, err
+	}
+	if rerr := q.rewrite(entries[n:]); err == nil {
+		err = rerr
+	}
+	return n, err
+}
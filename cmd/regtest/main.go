@@ -30,20 +30,30 @@ func main() {
 
 func dumpRegs(r *cc2500.Radio) {
 	fmt.Printf("\nConfiguration registers:\n")
-	regs := r.ReadConfiguration().Bytes()
-	resetValue := cc2500.ResetRFConfiguration.Bytes()
+	config := r.ReadConfiguration()
 	if r.Error() != nil {
 		log.Fatal(r.Error())
 	}
+	regs := config.Bytes()
+	fields := config.Decode()
+	resetFields := cc2500.ResetRFConfiguration.Decode()
 	for i, v := range regs {
-		fmt.Printf("%02X  %02X  %08b", cc2500.IOCFG2+i, v, v)
-		r := resetValue[i]
-		if v == r {
+		fmt.Printf("%02X  %-8s  %02X  %08b\n", cc2500.IOCFG2+i, cc2500.RegisterName(byte(i)), v, v)
+		for j, f := range fields {
+			if int(f.Register) != i || f.Reserved {
+				continue
+			}
+			fmt.Printf("      %-22s %s", f.Name, f.Describe(f.Value))
+			if f.Value != resetFields[j].Value {
+				fmt.Printf("  (reset: %s)", f.Describe(resetFields[j].Value))
+			}
 			fmt.Printf("\n")
-		} else {
-			fmt.Printf("  **** SHOULD BE %02X  %08b\n", r, r)
 		}
 	}
+	diff := cc2500.ResetRFConfiguration.Diff(config)
+	if diff != nil {
+		fmt.Printf("\n**** Registers differing from reset values:\n%v\n", diff)
+	}
 }
 
 func readRegs(r *cc2500.Radio) {
@@ -0,0 +1,109 @@
+package main
+
+// Load register settings exported from SmartRF Studio (or written in
+// the simple REGISTER=0xVV format) into the radio, compare them with
+// the registers of the live chip, or export the chip's current settings.
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/ecc1/cc2500"
+)
+
+var smartRF = flag.Bool("smartrf", false, "export in SmartRF Studio C header format")
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s [-smartrf] apply|diff|dump [file]\n", os.Args[0])
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() < 1 {
+		usage()
+	}
+	cmd := flag.Arg(0)
+	var settings cc2500.Settings
+	switch cmd {
+	case "apply", "diff":
+		if flag.NArg() != 2 {
+			usage()
+		}
+		var err error
+		settings, err = cc2500.LoadSettings(flag.Arg(1))
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "dump":
+		if flag.NArg() != 1 {
+			usage()
+		}
+	default:
+		usage()
+	}
+	r := cc2500.Open()
+	if r.Error() != nil {
+		log.Fatal(r.Error())
+	}
+	switch cmd {
+	case "apply":
+		r.WriteSettings(settings)
+		if r.Error() != nil {
+			log.Fatal(r.Error())
+		}
+		live := r.ReadSettings()
+		if r.Error() != nil {
+			log.Fatal(r.Error())
+		}
+		if diff(settings, live) {
+			os.Exit(1)
+		}
+	case "diff":
+		live := r.ReadSettings()
+		if r.Error() != nil {
+			log.Fatal(r.Error())
+		}
+		if diff(settings, live) {
+			os.Exit(1)
+		}
+	case "dump":
+		live := r.ReadSettings()
+		if r.Error() != nil {
+			log.Fatal(r.Error())
+		}
+		var err error
+		if *smartRF {
+			err = live.WriteSmartRF(os.Stdout)
+		} else {
+			err = live.Write(os.Stdout)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if r.Error() != nil {
+		log.Fatal(r.Error())
+	}
+}
+
+// diff prints the registers that differ between the settings
+// from the file and those read from the chip,
+// and reports whether there were any differences.
+func diff(want, live cc2500.Settings) bool {
+	d := want.Config.Diff(&live.Config)
+	if d != nil {
+		fmt.Println(d)
+	}
+	found := d != nil
+	if len(want.PATable) > len(live.PATable) || !bytes.Equal(want.PATable, live.PATable[:len(want.PATable)]) {
+		found = true
+		fmt.Printf("PATABLE: wrote % X, read % X\n", want.PATable, live.PATable)
+	}
+	return found
+}
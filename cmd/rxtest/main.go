@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"strconv"
@@ -23,8 +24,8 @@ func main() {
 	r.Init(frequency)
 	log.Printf("actual frequency: %d", r.Frequency())
 	for r.Error() == nil {
-		data, rssi := r.Receive(time.Hour)
-		log.Printf("% X (RSSI = %d)", data, rssi)
+		data, rssi, lqi := r.ReceiveContext(context.Background(), time.Hour)
+		log.Printf("% X (RSSI = %d, LQI = %d)", data, rssi, lqi)
 	}
 	log.Fatal(r.Error())
 }
@@ -2,18 +2,52 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"time"
 
 	"github.com/ecc1/cc2500"
+	"github.com/ecc1/cc2500/readinglog"
 )
 
 func main() {
-	r := cc2500.Open()
+	config := cc2500.DefaultConfig()
+	err := config.ApplyEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	configFile := flag.String("config", "", "read radio configuration from JSON `file`")
+	logDir := flag.String("log", "", "append readings to log in `directory`")
+	stateFile := flag.String("state", "", "save and restore frequency offsets in `file`")
+	wor := flag.Bool("wor", false, "let the radio sleep in Wake-on-Radio mode between readings")
+	sleep := flag.Bool("sleep", false, "put the radio to sleep between readings")
+	config.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if *configFile != "" {
+		err = config.ReadFile(*configFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		// Command-line flags take precedence over the file.
+		flag.Parse()
+	}
+	var rlog *readinglog.Writer
+	if *logDir != "" {
+		rlog, err = readinglog.Create(*logDir, 0)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer rlog.Close()
+	}
+	r := cc2500.OpenWithConfig(config)
 	log.Printf("connected to %s radio on %s", r.Name(), r.Device())
 	hours := time.Tick(1 * time.Hour)
-	readings := r.ReceiveReadings()
+	opts := cc2500.DefaultReceiverOptions()
+	opts.StateFile = *stateFile
+	opts.WakeOnRadio = *wor
+	opts.Sleep = *sleep
+	readings := r.ReceiveReadings(&opts)
 	numReadings := 0
 	for {
 		if r.Error() != nil {
@@ -27,6 +61,12 @@ func main() {
 			if reading != nil {
 				print(reading)
 				numReadings++
+				if rlog != nil {
+					err = rlog.Append(reading)
+					if err != nil {
+						log.Print(err)
+					}
+				}
 			}
 		}
 	}
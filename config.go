@@ -0,0 +1,87 @@
+package cc2500
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+)
+
+// Config describes how the radio is connected to the host.
+type Config struct {
+	SPIDevice    string `json:"spi_device"`    // pathname of SPI device
+	SPISpeed     int    `json:"spi_speed"`     // Hz
+	CustomCS     int    `json:"custom_cs"`     // GPIO for custom chip select (0 for default)
+	InterruptPin int    `json:"interrupt_pin"` // GPIO for receive interrupts
+}
+
+// DefaultConfig returns the configuration for this platform.
+func DefaultConfig() Config {
+	return Config{
+		SPIDevice:    spiDevice,
+		SPISpeed:     spiSpeed,
+		CustomCS:     customCS,
+		InterruptPin: interruptPin,
+	}
+}
+
+const (
+	spiDeviceEnvVar    = "CC2500_SPI_DEVICE"
+	spiSpeedEnvVar     = "CC2500_SPI_SPEED"
+	customCSEnvVar     = "CC2500_CUSTOM_CS"
+	interruptPinEnvVar = "CC2500_INTERRUPT_PIN"
+)
+
+// ApplyEnv overrides the configuration with the values of
+// any CC2500_SPI_DEVICE, CC2500_SPI_SPEED, CC2500_CUSTOM_CS,
+// and CC2500_INTERRUPT_PIN environment variables.
+func (c *Config) ApplyEnv() error {
+	if s := os.Getenv(spiDeviceEnvVar); s != "" {
+		c.SPIDevice = s
+	}
+	vars := []struct {
+		name string
+		val  *int
+	}{
+		{spiSpeedEnvVar, &c.SPISpeed},
+		{customCSEnvVar, &c.CustomCS},
+		{interruptPinEnvVar, &c.InterruptPin},
+	}
+	for _, v := range vars {
+		s := os.Getenv(v.name)
+		if s == "" {
+			continue
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("%s: %v", v.name, err)
+		}
+		*v.val = n
+	}
+	return nil
+}
+
+// ReadFile overrides the configuration with the fields
+// present in the given JSON file.
+func (c *Config) ReadFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(data, c)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+// RegisterFlags defines command-line flags in the given flag set
+// that override the configuration, using its current values as defaults.
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.SPIDevice, "spi", c.SPIDevice, "SPI `device`")
+	fs.IntVar(&c.SPISpeed, "spi-speed", c.SPISpeed, "SPI speed in Hz")
+	fs.IntVar(&c.CustomCS, "cs", c.CustomCS, "GPIO `pin` for custom chip select (0 for default)")
+	fs.IntVar(&c.InterruptPin, "interrupt", c.InterruptPin, "GPIO `pin` for receive interrupts")
+}
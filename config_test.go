@@ -0,0 +1,58 @@
+package cc2500
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigApplyEnv(t *testing.T) {
+	env := map[string]string{
+		spiDeviceEnvVar:    "/dev/spidev0.1",
+		spiSpeedEnvVar:     "4000000",
+		interruptPinEnvVar: "25",
+	}
+	for k, v := range env {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+	c := DefaultConfig()
+	c.CustomCS = 7
+	err := c.ApplyEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Config{SPIDevice: "/dev/spidev0.1", SPISpeed: 4000000, CustomCS: 7, InterruptPin: 25}
+	if c != want {
+		t.Errorf("ApplyEnv() == %+v, want %+v", c, want)
+	}
+	os.Setenv(customCSEnvVar, "x")
+	defer os.Unsetenv(customCSEnvVar)
+	err = c.ApplyEnv()
+	if err == nil {
+		t.Errorf("ApplyEnv() with %s=x succeeded", customCSEnvVar)
+	}
+}
+
+func TestConfigReadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cc2500")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "radio.json")
+	err = ioutil.WriteFile(path, []byte(`{"spi_device": "/dev/spidev0.0", "interrupt_pin": 24}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := Config{SPIDevice: "/dev/spidev1.0", SPISpeed: 6000000, InterruptPin: 22}
+	err = c.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Config{SPIDevice: "/dev/spidev0.0", SPISpeed: 6000000, InterruptPin: 24}
+	if c != want {
+		t.Errorf("ReadFile() == %+v, want %+v", c, want)
+	}
+}
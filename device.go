@@ -2,6 +2,7 @@ package cc2500
 
 import (
 	"log"
+	"time"
 
 	"github.com/ecc1/radio"
 )
@@ -10,26 +11,28 @@ const (
 	hwVersion = 0x8003
 )
 
-type hwFlavor struct{}
+type hwFlavor struct {
+	config Config
+}
 
 // SPIDevice returns the pathname of the radio's SPI device.
 func (f hwFlavor) SPIDevice() string {
-	return spiDevice
+	return f.config.SPIDevice
 }
 
 // Speed returns the radio's SPI speed.
 func (f hwFlavor) Speed() int {
-	return spiSpeed
+	return f.config.SPISpeed
 }
 
 // CustomCS returns the GPIO pin number to use as a custom chip-select for the radio.
 func (f hwFlavor) CustomCS() int {
-	return customCS
+	return f.config.CustomCS
 }
 
 // InterruptPin returns the GPIO pin number to use for receive interrupts.
 func (f hwFlavor) InterruptPin() int {
-	return interruptPin
+	return f.config.InterruptPin
 }
 
 // ReadSingleAddress returns the encoding of an address for SPI read operations.
@@ -56,17 +59,79 @@ func (f hwFlavor) WriteBurstAddress(addr byte) byte {
 	return BURST_MODE | addr
 }
 
-// Radio represents an open radio device.
-type Radio struct {
-	hw  *radio.Hardware
+// Hardware is the interface satisfied by the low-level register,
+// FIFO, and interrupt access underlying a Radio.
+// It is implemented by SPI-attached hardware and by Simulator.
+type Hardware interface {
+	Device() string
+
+	ReadRegister(addr byte) byte
+	ReadBurst(addr byte, n int) []byte
+	WriteRegister(addr byte, value byte)
+	WriteBurst(addr byte, data []byte)
+
+	// Strobe issues the given command strobe and returns the chip status byte.
+	// It does not change the hardware's error state.
+	Strobe(cmd byte) (byte, error)
+
+	AwaitInterrupt(timeout time.Duration)
+	ReadInterrupt() bool
+
+	Error() error
+	SetError(error)
+
+	Close()
+}
+
+// spiHardware implements Hardware for an SPI-attached CC2500 module.
+type spiHardware struct {
+	*radio.Hardware
 	snd []byte
 	rcv []byte
-	err error
 }
 
-// Open opens the radio device.
+func openSPI(flavor radio.HardwareFlavor) *spiHardware {
+	return &spiHardware{
+		Hardware: radio.Open(flavor),
+		snd:      make([]byte, 1),
+		rcv:      make([]byte, 1),
+	}
+}
+
+// Strobe writes the given command to the SPI device.
+func (h *spiHardware) Strobe(cmd byte) (byte, error) {
+	h.snd[0] = cmd
+	err := h.SPIDevice().Transfer(h.snd, h.rcv)
+	return h.rcv[0], err
+}
+
+// Radio represents an open radio device.
+type Radio struct {
+	hw     Hardware
+	err    error
+	status receiverStatus
+	verify bool // read back configuration after writing it
+
+	asleep bool      // in SLEEP or crystal-off state
+	saved  *Settings // registers to restore after SLEEP
+
+	metrics *Metrics // set while a G4 receiver is running
+}
+
+// Open opens the radio device using the default configuration.
 func Open() *Radio {
-	r := &Radio{hw: radio.Open(hwFlavor{})}
+	return OpenWithConfig(DefaultConfig())
+}
+
+// OpenWithConfig opens the radio device described by the given configuration.
+func OpenWithConfig(config Config) *Radio {
+	return OpenHardware(openSPI(hwFlavor{config: config}))
+}
+
+// OpenHardware opens a radio using the given hardware,
+// such as a Simulator.
+func OpenHardware(hw Hardware) *Radio {
+	r := &Radio{hw: hw}
 	v := r.Version()
 	if r.Error() != nil {
 		return r
@@ -76,8 +141,6 @@ func Open() *Radio {
 		r.SetError(radio.HardwareVersionError{Actual: v, Expected: hwVersion})
 		return r
 	}
-	r.snd = make([]byte, 1)
-	r.rcv = make([]byte, 1)
 	return r
 }
 
@@ -94,7 +157,7 @@ func (r *Radio) Name() string {
 
 // Device returns the pathname of the radio's device.
 func (r *Radio) Device() string {
-	return spiDevice
+	return r.hw.Device()
 }
 
 // Version returns the radio's hardware version.
@@ -109,9 +172,9 @@ func (r *Radio) Strobe(cmd byte) byte {
 	if verbose && cmd != SNOP {
 		log.Printf("issuing %s command", strobeName(cmd))
 	}
-	r.snd[0] = cmd
-	r.err = r.hw.SPIDevice().Transfer(r.snd, r.rcv)
-	return r.rcv[0]
+	var status byte
+	status, r.err = r.hw.Strobe(cmd)
+	return status
 }
 
 // Reset resets the radio device.
@@ -120,6 +183,7 @@ func (r *Radio) Reset() {
 }
 
 // Init initializes the radio device.
+// Use SetVerify beforehand to check that the configuration was written correctly.
 func (r *Radio) Init(frequency uint32) {
 	r.Reset()
 	r.InitRF(frequency)
@@ -140,7 +204,18 @@ func (r *Radio) SetError(err error) {
 	r.err = err
 }
 
-// Hardware returns the radio's hardware information.
+// Hardware returns the radio's hardware information,
+// or nil if the radio was not opened on SPI hardware.
 func (r *Radio) Hardware() *radio.Hardware {
+	h, ok := r.hw.(*spiHardware)
+	if !ok {
+		return nil
+	}
+	return h.Hardware
+}
+
+// HardwareInterface returns the Hardware underlying the radio,
+// such as a Simulator.
+func (r *Radio) HardwareInterface() Hardware {
 	return r.hw
 }
@@ -17,6 +17,15 @@ func (r *Radio) DumpRF() {
 	pa := r.ReadPATable()
 	n := r.hw.ReadRegister(FREND0) & FREND0_PA_POWER_MASK
 	log.Printf("PATABLE: % X using 0..%d", pa, n)
+	config := r.ReadConfiguration()
+	if config == nil {
+		return
+	}
+	for _, v := range config.Decode() {
+		if !v.Reserved {
+			log.Print(v)
+		}
+	}
 }
 
 func (r *Radio) showFreqSynthControl() {
@@ -28,10 +37,11 @@ func (r *Radio) showModemConfig() {
 	chanbw, drate := r.ReadChannelParams()
 	log.Printf("Channel bandwidth: %d Hz", chanbw)
 	log.Printf("Data rate: %d Baud", drate)
+	log.Printf("Deviation: %d Hz", r.ReadDeviation())
 
 	m2 := r.hw.ReadRegister(MDMCFG2)
 	showBoolCondition("DC blocking filter", m2&MDMCFG2_DEM_DCFILT_OFF == 0)
-	showBoolCondition("Manchester encoding", m2&(1<<3) != 0)
+	showBoolCondition("Manchester encoding", m2&MDMCFG2_MANCHESTER_EN != 0)
 	log.Printf("Modulation format: %s", modFormat[(m2&MDMCFG2_MOD_FORMAT_MASK)>>4])
 	log.Printf("Sync mode: %s", syncMode[m2&MDMCFG2_SYNC_MODE_MASK])
 
@@ -1,23 +1,32 @@
 package cc2500
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 )
 
 const (
-	baseFrequency = 2425000000
+	// BaseFrequency is the frequency of G4 channel number 0, in Hertz.
+	BaseFrequency = 2425000000
 
-	readingInterval = 5 * time.Minute
-	channelInterval = 500 * time.Millisecond
+	defaultReadingInterval = 5 * time.Minute
+	defaultChannelInterval = 500 * time.Millisecond
+	defaultWakeupMargin    = 100 * time.Millisecond
 
-	wakeupMargin = 100 * time.Millisecond
+	// Largest FREQEST value (about 25 kHz) accepted as a frequency correction.
+	maxFreqEst = 16
 
-	slowWait = readingInterval + 1*time.Minute
-	fastWait = channelInterval + 50*time.Millisecond
-	syncWait = wakeupMargin + 100*time.Millisecond
+	// The calibrated RC oscillator used for Wake-on-Radio
+	// is accurate to about 1 part in worAccuracy.
+	worAccuracy = 100
+
+	// Readings buffered for each reader; further readings are dropped.
+	readingBuffer = 10
 
 	verboseG4 = false
 )
@@ -25,8 +34,8 @@ const (
 type (
 	// Channel contains information about a channel register and frequency offset.
 	Channel struct {
-		number uint8 // CHANNR value
-		offset uint8 // FSCTRL0 value
+		Number uint8 // CHANNR value
+		Offset uint8 // FSCTRL0 value
 	}
 )
 
@@ -42,82 +51,561 @@ var (
 	}
 )
 
-func (r *Radio) changeChannel(i int) {
-	c := Channels[i]
+// ReceiverOptions contains the settings for receiving G4 readings.
+// Zero-valued fields are replaced by their defaults.
+type ReceiverOptions struct {
+	// TransmitterID is the 5-character ID of the transmitter to listen for.
+	// If it is empty, readings from any transmitter are accepted
+	// and no attempt is made to stay in sync.
+	TransmitterID string
+
+	ReadingInterval time.Duration // time between readings
+	ChannelInterval time.Duration // time between channels within a reading
+	WakeupMargin    time.Duration // how early to listen for an expected reading
+
+	// Channels lists the channels of each reading, in transmission order.
+	Channels []Channel
+
+	// Metrics, if not nil, is updated by the receiver.
+	Metrics *Metrics
+
+	// StateFile, if not empty, is the pathname of a file in which
+	// the receiver saves the frequency offsets it learns for each
+	// transmitter, and from which it restores them when it starts.
+	// Changes are written at most every 10 minutes and when it stops.
+	StateFile string
+
+	// WakeOnRadio, if true, lets the radio sleep in Wake-on-Radio mode
+	// while waiting for the next reading of a synced transmitter,
+	// instead of staying in IDLE until it is time to listen.
+	// The TEST2, TEST1, and TEST0 settings lost in sleep are restored
+	// at the wakeup time, before the reading is expected.
+	WakeOnRadio bool
+
+	// Sleep, if true, puts the radio into SLEEP state while waiting
+	// for the next reading of a synced transmitter, and wakes it
+	// in time to listen. WakeOnRadio takes precedence.
+	Sleep bool
+}
+
+// DefaultReceiverOptions returns the default receiver options,
+// using the DEXCOM_G4_XMTR_ID environment variable (if set)
+// as the transmitter ID.
+func DefaultReceiverOptions() ReceiverOptions {
+	opts := ReceiverOptions{TransmitterID: os.Getenv(transmitterIDEnvVar)}
+	opts.setDefaults()
+	return opts
+}
+
+func (opts *ReceiverOptions) setDefaults() {
+	if opts.ReadingInterval == 0 {
+		opts.ReadingInterval = defaultReadingInterval
+	}
+	if opts.ChannelInterval == 0 {
+		opts.ChannelInterval = defaultChannelInterval
+	}
+	if opts.WakeupMargin == 0 {
+		opts.WakeupMargin = defaultWakeupMargin
+	}
+	if opts.Channels == nil {
+		opts.Channels = append([]Channel(nil), Channels...)
+	}
+}
+
+// Validate checks the options for consistency.
+func (opts ReceiverOptions) Validate() error {
+	if opts.TransmitterID != "" {
+		err := ValidateTransmitterID(opts.TransmitterID)
+		if err != nil {
+			return err
+		}
+	}
+	if opts.ReadingInterval < 0 || opts.ChannelInterval < 0 || opts.WakeupMargin < 0 {
+		return errors.New("receiver intervals must not be negative")
+	}
+	if opts.Channels != nil && len(opts.Channels) == 0 {
+		return errors.New("no receiver channels")
+	}
+	return nil
+}
+
+// ValidateTransmitterID checks that id is a 5-character G4 transmitter ID.
+func ValidateTransmitterID(id string) error {
+	if len(id) != 5 {
+		return fmt.Errorf("transmitter ID %q must be 5 characters", id)
+	}
+	for i := 0; i < len(id); i++ {
+		if !strings.ContainsRune(string(transmitterIDChar), rune(id[i])) {
+			return fmt.Errorf("transmitter ID %q contains invalid character %q", id, id[i])
+		}
+	}
+	return nil
+}
+
+// Maximum time to wait for a reading when out of sync.
+func (opts *ReceiverOptions) slowWait() time.Duration {
+	return opts.ReadingInterval + 1*time.Minute
+}
+
+// Maximum time to wait for the next channel of a reading.
+func (opts *ReceiverOptions) fastWait() time.Duration {
+	return opts.ChannelInterval + 50*time.Millisecond
+}
+
+// Maximum time to wait for an expected reading when in sync.
+func (opts *ReceiverOptions) syncWait() time.Duration {
+	return opts.WakeupMargin + 100*time.Millisecond
+}
+
+// transmitter holds the sync state and frequency calibration
+// for one G4 transmitter.
+type transmitter struct {
+	id          string
+	inSync      bool
+	lastReading time.Time // when the packet on channel 0 was sent
+	offsets     []uint8   // FSCTRL0 value for each channel
+	readings    chan *Packet
+}
+
+func newTransmitter(id string, channels []Channel) *transmitter {
+	x := &transmitter{
+		id:       id,
+		offsets:  make([]uint8, len(channels)),
+		readings: make(chan *Packet, readingBuffer),
+	}
+	for i, c := range channels {
+		x.offsets[i] = c.Offset
+	}
+	return x
+}
+
+func (r *Radio) changeChannel(c Channel, i int, offset uint8) {
 	if verboseG4 {
 		log.Printf("changing to channel %d", i)
-		printFrequency("offset ", c.offset)
+		printFrequency("offset ", offset)
 	}
-	r.hw.WriteRegister(CHANNR, c.number)
-	r.hw.WriteRegister(FSCTRL0, c.offset)
+	r.hw.WriteRegister(CHANNR, c.Number)
+	r.hw.WriteRegister(FSCTRL0, offset)
 }
 
-func (r *Radio) adjustFrequency(i int) {
-	c := &Channels[i]
-	freqEst := r.hw.ReadRegister(FREQEST)
-	offset := r.hw.ReadRegister(FSCTRL0)
-	c.offset = offset + freqEst
-	r.hw.WriteRegister(FSCTRL0, c.offset)
+// adjustFrequency applies half of the frequency error estimated
+// for the last packet (rounded away from zero) to the offset for channel i,
+// so that a single noisy estimate cannot pull the channel far off frequency.
+// Estimates larger than maxFreqEst are rejected as outliers.
+// It returns true if the offset was changed.
+func (r *Radio) adjustFrequency(x *transmitter, i int, freqEst byte) bool {
+	e := int8(freqEst)
 	if verboseG4 {
 		printFrequency("FREQEST", freqEst)
+	}
+	if e == 0 || e > maxFreqEst || e < -maxFreqEst {
+		return false
+	}
+	step := e/2 + e%2
+	offset := r.hw.ReadRegister(FSCTRL0)
+	x.offsets[i] = offset + byte(step)
+	r.hw.WriteRegister(FSCTRL0, x.offsets[i])
+	if verboseG4 {
 		printFrequency("FSCTRL0", offset)
-		printFrequency("offset ", c.offset)
+		printFrequency("offset ", x.offsets[i])
 	}
+	return true
 }
 
 func printFrequency(label string, f byte) {
 	log.Printf("%s = %d Hz (%X)", label, registerToFrequencyOffset(f), f)
 }
 
-func (r *Radio) scanChannels(readings chan<- *Packet, sync bool) {
-	inSync := false
-	lastReading := time.Time{}
-	r.Init(baseFrequency)
-	for {
-		waitTime := slowWait
-		var p *Packet
-		for n := range Channels {
-			if verboseG4 {
-				log.Printf("listening on channel %d; sync = %v", n, inSync)
+// CRC8Error indicates a G4 packet with an incorrect CRC8 checksum.
+type CRC8Error struct {
+	Data     []byte
+	Computed byte
+	Received byte
+}
+
+func (e CRC8Error) Error() string {
+	return fmt.Sprintf("computed CRC %02X but received %02X", e.Computed, e.Received)
+}
+
+// TransmitterError indicates a packet from a G4 transmitter
+// other than the one being listened for.
+type TransmitterError struct {
+	TransmitterID string
+}
+
+func (e TransmitterError) Error() string {
+	return fmt.Sprintf("ignoring packet from transmitter %s", e.TransmitterID)
+}
+
+// DroppedReadingError indicates a reading (or missed reading) that was
+// discarded because its transmitter's channel was full.
+type DroppedReadingError struct {
+	TransmitterID string
+}
+
+func (e DroppedReadingError) Error() string {
+	return fmt.Sprintf("dropped reading for transmitter %s: channel full", e.TransmitterID)
+}
+
+// ReceiveError describes an error that occurred while listening for readings.
+type ReceiveError struct {
+	Timestamp time.Time
+	Channel   int
+	Err       error
+}
+
+func (e *ReceiveError) Error() string {
+	return fmt.Sprintf("channel %d: %v", e.Channel, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ReceiveError) Unwrap() error {
+	return e.Err
+}
+
+// Deliver an error on the errs channel, if any, without blocking.
+// Otherwise, log it.
+func reportError(errs chan<- *ReceiveError, channel int, err error) {
+	if errs == nil {
+		log.Print(err)
+		return
+	}
+	select {
+	case errs <- &ReceiveError{Timestamp: time.Now(), Channel: channel, Err: err}:
+	default:
+	}
+}
+
+// scanner listens for G4 packets on behalf of a set of transmitters.
+// If the set is empty, packets from any transmitter are accepted
+// and no attempt is made to stay in sync.
+type scanner struct {
+	r       *Radio
+	opts    ReceiverOptions
+	ctx     context.Context
+	errs    chan<- *ReceiveError
+	xmtrs   []*transmitter
+	byID    map[string]*transmitter
+	any     chan *Packet // readings when xmtrs is empty
+	offsets []uint8      // FSCTRL0 values when xmtrs is empty
+
+	lastRSSI   int
+	lastPacket time.Time
+
+	// If not zero, the next listen uses Wake-on-Radio until this time.
+	worWakeup time.Time
+
+	// Offsets not yet written to the state file, and the time of the last write.
+	offsetsDirty bool
+	offsetsSaved time.Time
+}
+
+func (r *Radio) newScanner(ctx context.Context, opts ReceiverOptions, ids []string, errs chan<- *ReceiveError) *scanner {
+	opts.setDefaults()
+	s := &scanner{
+		r:    r,
+		opts: opts,
+		ctx:  ctx,
+		errs: errs,
+		byID: make(map[string]*transmitter),
+	}
+	for _, id := range ids {
+		if s.byID[id] != nil {
+			continue
+		}
+		x := newTransmitter(id, opts.Channels)
+		s.xmtrs = append(s.xmtrs, x)
+		s.byID[id] = x
+	}
+	if len(s.xmtrs) == 0 {
+		s.any = make(chan *Packet, readingBuffer)
+		s.offsets = newTransmitter("", opts.Channels).offsets
+	}
+	s.restoreOffsets()
+	return s
+}
+
+func (s *scanner) run() {
+	r := s.r
+	defer func() {
+		for _, x := range s.xmtrs {
+			close(x.readings)
+		}
+		if s.any != nil {
+			close(s.any)
+		}
+		if s.errs != nil {
+			close(s.errs)
+		}
+	}()
+	defer func() {
+		s.flushOffsets()
+		r.Strobe(SIDLE)
+		r.SetError(nil)
+		s.publish("", 0)
+		r.metrics = nil
+	}()
+	r.metrics = s.opts.Metrics
+	r.metrics.start(len(s.opts.Channels))
+	r.Init(BaseFrequency)
+	for s.ctx.Err() == nil {
+		if s.any != nil {
+			r.changeChannel(s.opts.Channels[0], 0, s.offsets[0])
+			p, _, _ := s.listen(0, s.opts.slowWait())
+			if !s.send(s.any, "", p) {
+				return
 			}
-			r.changeChannel(n)
-			if n == 0 && inSync {
-				syncSleep(lastReading)
-				waitTime = syncWait
+			continue
+		}
+		if !s.step() {
+			return
+		}
+	}
+}
+
+// step performs one scheduling decision: search for transmitters
+// that are out of sync, or wait for the next synced transmitter's reading.
+// It returns false if the context was cancelled.
+func (s *scanner) step() bool {
+	var next *transmitter
+	var unsynced []*transmitter
+	for _, x := range s.xmtrs {
+		if !x.inSync {
+			unsynced = append(unsynced, x)
+		} else if next == nil || x.lastReading.Before(next.lastReading) {
+			next = x
+		}
+	}
+	slowWait := s.opts.slowWait()
+	if len(unsynced) != 0 {
+		// Stop searching in time to listen for the synced transmitter.
+		wait := slowWait
+		deadline := time.Time{}
+		if next != nil {
+			deadline = s.wakeupTime(next)
+			wait = time.Until(deadline)
+		}
+		if wait > slowWait {
+			wait = slowWait
+		}
+		if wait > 0 {
+			p := s.hopUntil(unsynced[0], wait, nil, deadline)
+			if p != nil || wait < slowWait {
+				return s.ctx.Err() == nil
 			}
-			data, rssi := r.Receive(waitTime)
-			p = r.checkPacket(n, data, rssi)
-			err := r.Error()
-			if err != nil && err != ErrReceiveTimeout {
-				log.Print(err)
+			for _, x := range unsynced {
+				if !s.send(x.readings, x.id, nil) {
+					return false
+				}
 			}
-			r.SetError(nil)
-			if !sync {
+			return true
+		}
+	}
+	wakeup := s.wakeupTime(next)
+	switch {
+	case s.opts.WakeOnRadio:
+		s.worWakeup = wakeup
+	case s.opts.Sleep:
+		if !s.sleep(wakeup) {
+			return false
+		}
+	default:
+		if !syncSleep(s.ctx, wakeup) {
+			return false
+		}
+	}
+	p := s.hop(next, s.opts.syncWait(), next)
+	if p == nil && s.ctx.Err() == nil {
+		next.inSync = false
+		s.publish("IDLE", 0)
+		return s.send(next.readings, next.id, nil)
+	}
+	return s.ctx.Err() == nil
+}
+
+// sleep puts the radio into SLEEP state until the given wakeup time.
+// It returns false if the context was cancelled first.
+func (s *scanner) sleep(wakeup time.Time) bool {
+	r := s.r
+	s.publish("SLEEP", 0)
+	r.Sleep()
+	ok := syncSleep(s.ctx, wakeup)
+	r.Wake()
+	if r.Error() != nil {
+		reportError(s.errs, 0, r.Error())
+		r.SetError(nil)
+	}
+	s.publish("IDLE", 0)
+	return ok
+}
+
+// Time at which to start listening for the transmitter's next reading.
+func (s *scanner) wakeupTime(x *transmitter) time.Time {
+	return x.lastReading.Add(s.opts.ReadingInterval - s.opts.WakeupMargin)
+}
+
+// hop listens on each channel in turn, using the frequency offsets of x,
+// with the given wait on channel 0 and fastWait on the others.
+// It stops after a packet from the target transmitter is received,
+// or from any configured transmitter if target is nil.
+// Packets from configured transmitters are delivered as they arrive.
+func (s *scanner) hop(x *transmitter, firstWait time.Duration, target *transmitter) *Packet {
+	return s.hopUntil(x, firstWait, target, time.Time{})
+}
+
+// hopUntil is like hop, but if the deadline is not zero,
+// it stops listening when the deadline is reached.
+func (s *scanner) hopUntil(x *transmitter, firstWait time.Duration, target *transmitter, deadline time.Time) *Packet {
+	wait := firstWait
+	for n, c := range s.opts.Channels {
+		if !deadline.IsZero() {
+			left := time.Until(deadline)
+			if left <= 0 {
 				break
 			}
-			if p != nil {
-				inSync = true
-				lastReading = p.Timestamp.Add(-time.Duration(n) * channelInterval)
-				r.adjustFrequency(n)
-				break
+			if wait > left {
+				wait = left
 			}
-			waitTime = fastWait
 		}
-		readings <- p
-		if p == nil {
-			inSync = false
+		if verboseG4 {
+			log.Printf("listening on channel %d for %v", n, wait)
 		}
+		s.r.changeChannel(c, n, x.offsets[n])
+		p, freqEst, err := s.listen(n, wait)
+		if err != nil {
+			return nil
+		}
+		if p != nil {
+			y := s.byID[p.TransmitterID]
+			if y == nil {
+				err := TransmitterError{TransmitterID: p.TransmitterID}
+				s.opts.Metrics.listened(n, nil, err)
+				reportError(s.errs, n, err)
+			} else {
+				y.inSync = true
+				y.lastReading = p.Timestamp.Add(-time.Duration(n) * s.opts.ChannelInterval)
+				if s.r.adjustFrequency(y, n, freqEst) {
+					s.offsetsChanged()
+				}
+				s.publish("IDLE", n)
+				if !s.send(y.readings, y.id, p) {
+					return nil
+				}
+				if target == nil || target == y {
+					return p
+				}
+			}
+		}
+		wait = s.opts.fastWait()
 	}
+	return nil
 }
 
-func syncSleep(lastReading time.Time) {
-	t := time.Now().Add(wakeupMargin)
-	sleepTime := lastReading.Add(readingInterval).Sub(t)
-	if sleepTime > 0 {
-		if verboseG4 {
-			log.Printf("sleeping for %v", sleepTime)
+// listen receives and checks a packet on channel n,
+// reporting any error other than a timeout.
+// It returns the packet and the FREQEST register value.
+// It returns a non-nil error only if the context was cancelled.
+func (s *scanner) listen(n int, wait time.Duration) (*Packet, byte, error) {
+	r := s.r
+	s.publish("RX", n)
+	var data []byte
+	var rssi int
+	var lqi uint8
+	if !s.worWakeup.IsZero() {
+		data, rssi, lqi = s.receiveWakeOnRadio(wait)
+	} else {
+		data, rssi, lqi = r.ReceiveContext(s.ctx, wait)
+	}
+	p := r.checkPacket(n, data, rssi)
+	freqEst := byte(0)
+	if p != nil {
+		freqEst = r.hw.ReadRegister(FREQEST)
+		p.LQI = lqi
+		p.FreqEst = registerToFrequencyOffset(freqEst)
+		s.lastRSSI = p.RSSI
+		s.lastPacket = p.Timestamp
+	}
+	s.publish("IDLE", n)
+	err := r.Error()
+	r.SetError(nil)
+	if s.ctx.Err() != nil {
+		return nil, 0, s.ctx.Err()
+	}
+	s.opts.Metrics.listened(n, p, err)
+	if err != nil && err != ErrReceiveTimeout {
+		reportError(s.errs, n, err)
+	}
+	return p, freqEst, nil
+}
+
+// receiveWakeOnRadio lets the radio sleep in Wake-on-Radio mode
+// until s.worWakeup and then listen for the given wait.
+// The chip listens with no RX timeout from Event 0 until
+// the host-side wait ends, so a long sleep is not limited
+// by the short RX timeouts available at coarse WOR resolutions.
+func (s *scanner) receiveWakeOnRadio(wait time.Duration) ([]byte, int, uint8) {
+	r := s.r
+	wakeup := s.worWakeup
+	s.worWakeup = time.Time{}
+	sleep := time.Until(wakeup)
+	if sleep <= 0 {
+		return r.ReceiveContext(s.ctx, wait)
+	}
+	// Schedule Event 0 early enough that the chip is listening
+	// by the wakeup time even if its RC oscillator runs slow.
+	margin := sleep / worAccuracy
+	interval, _ := r.SetWakeOnRadio(sleep-2*margin, 0)
+	if verboseG4 {
+		log.Printf("sleeping in Wake-on-Radio mode for %v", interval)
+	}
+	data, rssi, lqi := r.ReceiveWakeOnRadioAt(s.ctx, wakeup, wait)
+	err := r.Error()
+	r.SetError(nil)
+	r.ClearWakeOnRadio()
+	if err != nil {
+		r.SetError(err)
+	}
+	return data, rssi, lqi
+}
+
+// send delivers a reading for the given transmitter without blocking,
+// so that a reader that falls behind cannot stall the others.
+// If the channel is full, the reading is dropped and a DroppedReadingError
+// is reported. It returns false if the context has been cancelled.
+func (s *scanner) send(readings chan<- *Packet, id string, p *Packet) bool {
+	if s.ctx.Err() != nil {
+		return false
+	}
+	select {
+	case readings <- p:
+	default:
+		channel := 0
+		if p != nil {
+			id = p.TransmitterID
+			channel = p.Channel
 		}
-		time.Sleep(sleepTime)
+		reportError(s.errs, channel, DroppedReadingError{TransmitterID: id})
+	}
+	return true
+}
+
+// syncSleep sleeps until the given wakeup time.
+// It returns false if the context was cancelled first.
+func syncSleep(ctx context.Context, wakeup time.Time) bool {
+	sleepTime := time.Until(wakeup)
+	if sleepTime <= 0 {
+		return ctx.Err() == nil
+	}
+	if verboseG4 {
+		log.Printf("sleeping for %v", sleepTime)
+	}
+	timer := time.NewTimer(sleepTime)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
 	}
 }
 
@@ -126,47 +614,169 @@ func (r *Radio) checkPacket(channel int, data []byte, rssi int) *Packet {
 		return nil
 	}
 	if len(data) != packetLength {
-		r.SetError(fmt.Errorf("unexpected %d-byte packet: % X", len(data), data))
+		r.SetError(LengthError{Data: data, Expected: packetLength, RSSI: rssi})
 		return nil
 	}
 	pktCRC := data[packetLength-1]
 	calcCRC := CRC8(data[11 : packetLength-1])
 	if calcCRC != pktCRC {
-		r.SetError(fmt.Errorf("computed CRC %02X but received %02X", calcCRC, pktCRC))
+		r.SetError(CRC8Error{Data: data, Computed: calcCRC, Received: pktCRC})
 		return nil
 	}
-	p := unmarshalPacket(time.Now(), channel, data, rssi)
-	if p.TransmitterID != transmitterID && transmitterID != "" {
-		r.SetError(fmt.Errorf("ignoring packet from transmitter %s", p.TransmitterID))
-		return nil
-	}
-	return p
+	return unmarshalPacket(time.Now(), channel, data, rssi)
 }
 
 // ReceiveReadings starts a goroutine to listen for incoming packets
 // and returns a channel that can be used to receive them.
-func (r *Radio) ReceiveReadings() <-chan *Packet {
-	var sync bool
-	if transmitterID == "" {
-		log.Printf("receiving readings from any G4 transmitter (%s environment variable not set)", transmitterIDEnvVar)
-		sync = false
+// If opts is nil, DefaultReceiverOptions are used.
+// If the options are invalid, the radio's error state is set
+// and the returned channel is closed.
+func (r *Radio) ReceiveReadings(opts *ReceiverOptions) <-chan *Packet {
+	return r.ReceiveReadingsContext(context.Background(), opts)
+}
+
+// ReceiveReadingsContext is like ReceiveReadings, but stops listening
+// when the context is cancelled. The radio is then left in the IDLE state
+// and the channel is closed, after which the radio may be reused or closed.
+func (r *Radio) ReceiveReadingsContext(ctx context.Context, opts *ReceiverOptions) <-chan *Packet {
+	readings, _ := r.receiveReadings(ctx, opts, false)
+	return readings
+}
+
+// ReceiveReadingsWithErrors is like ReceiveReadingsContext, but also returns
+// a channel on which errors other than timeouts are delivered instead of
+// being logged. Errors are dropped if that channel's buffer is full.
+// Both channels are closed when the context is cancelled.
+func (r *Radio) ReceiveReadingsWithErrors(ctx context.Context, opts *ReceiverOptions) (<-chan *Packet, <-chan *ReceiveError) {
+	return r.receiveReadings(ctx, opts, true)
+}
+
+func (r *Radio) receiveReadings(ctx context.Context, opts *ReceiverOptions, withErrors bool) (<-chan *Packet, <-chan *ReceiveError) {
+	if opts == nil {
+		defaults := DefaultReceiverOptions()
+		opts = &defaults
+	}
+	var errs chan *ReceiveError
+	if withErrors {
+		errs = make(chan *ReceiveError, 10)
+	}
+	err := opts.Validate()
+	if err != nil {
+		r.SetError(err)
+		readings := make(chan *Packet)
+		close(readings)
+		if errs != nil {
+			close(errs)
+		}
+		return readings, errs
+	}
+	var ids []string
+	if opts.TransmitterID == "" {
+		log.Printf("receiving readings from any G4 transmitter")
 	} else {
-		log.Printf("receiving readings from G4 transmitter %s", transmitterID)
-		sync = true
+		log.Printf("receiving readings from G4 transmitter %s", opts.TransmitterID)
+		ids = []string{opts.TransmitterID}
+	}
+	s := r.newScanner(ctx, *opts, ids, errs)
+	go s.run()
+	if s.any != nil {
+		return s.any, errs
+	}
+	return s.xmtrs[0].readings, errs
+}
+
+// ReceiveTransmitters starts a goroutine to listen for readings
+// from the given G4 transmitters, keeping a separate reading schedule,
+// channel hop timing, and frequency calibration for each one.
+// It returns a channel of readings for each transmitter ID,
+// and a channel on which errors are delivered as in ReceiveReadingsWithErrors.
+// A nil reading is delivered when a transmitter's expected reading is missed.
+// If ids is empty, readings from any transmitter are delivered
+// on the channel for the empty ID.
+// The TransmitterID field of opts is ignored; if opts is nil,
+// DefaultReceiverOptions are used.
+// All channels are closed when the context is cancelled.
+// Each channel buffers a few readings; if one is not read, further
+// readings for it are dropped and reported as DroppedReadingError
+// rather than delaying the other transmitters.
+// If any ID or option is invalid, the radio's error state is set
+// and the returned channels are already closed.
+func (r *Radio) ReceiveTransmitters(ctx context.Context, ids []string, opts *ReceiverOptions) (map[string]<-chan *Packet, <-chan *ReceiveError) {
+	var o ReceiverOptions
+	if opts == nil {
+		o = DefaultReceiverOptions()
+	} else {
+		o = *opts
+	}
+	o.TransmitterID = ""
+	err := o.Validate()
+	for _, id := range ids {
+		if err == nil {
+			err = ValidateTransmitterID(id)
+		}
+	}
+	if err != nil {
+		r.SetError(err)
+		readings := make(map[string]<-chan *Packet)
+		closed := make(chan *Packet)
+		close(closed)
+		if len(ids) == 0 {
+			readings[""] = closed
+		}
+		for _, id := range ids {
+			readings[id] = closed
+		}
+		errs := make(chan *ReceiveError)
+		close(errs)
+		return readings, errs
+	}
+	errs := make(chan *ReceiveError, 10)
+	s := r.newScanner(ctx, o, ids, errs)
+	readings := make(map[string]<-chan *Packet)
+	for _, x := range s.xmtrs {
+		readings[x.id] = x.readings
+	}
+	if s.any != nil {
+		readings[""] = s.any
+	}
+	go s.run()
+	return readings, errs
+}
+
+// SendReading transmits the given packet on each channel in turn,
+// at the channel interval given by opts, as a G4 transmitter does.
+// If opts is nil, DefaultReceiverOptions are used.
+// Zero Port and DeviceInfo fields are sent as the usual values (3F and 03).
+// The radio should first be initialized with Init(BaseFrequency).
+func (r *Radio) SendReading(p *Packet, opts *ReceiverOptions) {
+	var o ReceiverOptions
+	if opts != nil {
+		o = *opts
+	}
+	o.setDefaults()
+	q := *p
+	if q.Port == 0 {
+		q.Port = defaultPort
+	}
+	if q.DeviceInfo == 0 {
+		q.DeviceInfo = defaultDeviceInfo
+	}
+	data, err := q.MarshalBinary()
+	if err != nil {
+		r.SetError(err)
+		return
+	}
+	start := time.Now()
+	for n, c := range o.Channels {
+		time.Sleep(time.Until(start.Add(time.Duration(n) * o.ChannelInterval)))
+		r.changeChannel(c, n, c.Offset)
+		r.Send(data)
+		if r.Error() != nil {
+			return
+		}
 	}
-	readings := make(chan *Packet, 10)
-	go r.scanChannels(readings, sync)
-	return readings
 }
 
 const (
 	transmitterIDEnvVar = "DEXCOM_G4_XMTR_ID"
 )
-
-var (
-	transmitterID = ""
-)
-
-func init() {
-	transmitterID = os.Getenv(transmitterIDEnvVar)
-}
@@ -0,0 +1,368 @@
+package cc2500
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReceiveReadings(t *testing.T) {
+	r, s := openSimulator(t)
+	s.Inject(SimPacket{Data: p3, RSSI: -60, LQI: 0x15, FreqEst: 0xFE})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	readings := r.ReceiveReadingsContext(ctx, &ReceiverOptions{})
+	select {
+	case p := <-readings:
+		if p == nil {
+			t.Fatal("ReceiveReadings() returned nil packet")
+		}
+		if p.TransmitterID != "6GN7J" || p.Sequence != 0xD6 || p.RSSI != -60 || p.LQI != 0x15 {
+			t.Errorf("ReceiveReadings() == %+v", *p)
+		}
+		if p.FreqEst != registerToFrequencyOffset(0xFE) {
+			t.Errorf("FreqEst == %d, want %d", p.FreqEst, registerToFrequencyOffset(0xFE))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ReceiveReadings() timed out")
+	}
+}
+
+func TestReceiveReadingsCancel(t *testing.T) {
+	r, _ := openSimulator(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	readings := r.ReceiveReadingsContext(ctx, &ReceiverOptions{})
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-readings:
+			if ok {
+				continue
+			}
+			if r.Error() != nil {
+				t.Errorf("error after cancellation: %v", r.Error())
+			}
+			if r.State() != "IDLE" {
+				t.Errorf("state after cancellation == %s, want IDLE", r.State())
+			}
+			return
+		case <-timeout:
+			t.Fatal("readings channel not closed after cancellation")
+		}
+	}
+}
+
+func TestSyncSleepCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if syncSleep(ctx, time.Now().Add(time.Minute)) {
+		t.Errorf("syncSleep() with cancelled context returned true")
+	}
+}
+
+func TestReceiveReadingsWithErrors(t *testing.T) {
+	r, s := openSimulator(t)
+	badCRC8 := append([]byte(nil), p1...)
+	badCRC8[packetLength-1] ^= 0xFF
+	s.Inject(SimPacket{Data: p1, RSSI: -80, BadCRC: true})
+	s.Inject(SimPacket{Data: badCRC8, RSSI: -70})
+	s.Inject(SimPacket{Data: p1[:10], RSSI: -60})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, errs := r.ReceiveReadingsWithErrors(ctx, &ReceiverOptions{})
+	for i := 0; i < 3; i++ {
+		var err *ReceiveError
+		select {
+		case err = <-errs:
+		case <-time.After(time.Second):
+			t.Fatalf("error %d not received", i)
+		}
+		switch i {
+		case 0:
+			var e CRCError
+			if !errors.As(err, &e) || e.RSSI != -80 {
+				t.Errorf("error %d == %v, want CRCError with RSSI -80", i, err)
+			}
+		case 1:
+			var e CRC8Error
+			if !errors.As(err, &e) || e.Computed != p1[packetLength-1] || e.Received != badCRC8[packetLength-1] {
+				t.Errorf("error %d == %v, want CRC8Error", i, err)
+			}
+		case 2:
+			var e LengthError
+			if !errors.As(err, &e) || len(e.Data) != 10 || e.Expected != packetLength {
+				t.Errorf("error %d == %v, want LengthError", i, err)
+			}
+		}
+	}
+}
+
+func TestReceiveReadingsForeignTransmitter(t *testing.T) {
+	r, s := openSimulator(t)
+	s.Inject(SimPacket{Data: p3, RSSI: -60})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, errs := r.ReceiveReadingsWithErrors(ctx, &ReceiverOptions{TransmitterID: "67LDE"})
+	select {
+	case err := <-errs:
+		var e TransmitterError
+		if !errors.As(err, &e) || e.TransmitterID != "6GN7J" {
+			t.Errorf("error == %v, want TransmitterError for 6GN7J", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("error not received")
+	}
+}
+
+func TestReceiveTransmitters(t *testing.T) {
+	r, s := openSimulator(t)
+	s.Inject(SimPacket{Data: p1, RSSI: -70, FreqEst: 0x04})
+	s.Inject(SimPacket{Data: p5, RSSI: -60})
+	s.Inject(SimPacket{Data: p3, RSSI: -50})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	readings, errs := r.ReceiveTransmitters(ctx, []string{"67LDE", "6GN7J"}, nil)
+	if len(readings) != 2 {
+		t.Fatalf("ReceiveTransmitters() returned %d channels, want 2", len(readings))
+	}
+	for _, id := range []string{"67LDE", "6GN7J"} {
+		select {
+		case p := <-readings[id]:
+			if p == nil || p.TransmitterID != id {
+				t.Errorf("reading for %s == %+v", id, p)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no reading for %s", id)
+		}
+	}
+	select {
+	case err := <-errs:
+		var e TransmitterError
+		if !errors.As(err, &e) || e.TransmitterID != "63KDG" {
+			t.Errorf("error == %v, want TransmitterError for 63KDG", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("error not received")
+	}
+}
+
+func TestReceiveTransmittersUnreadChannel(t *testing.T) {
+	r, sim := openSimulator(t)
+	errs := make(chan *ReceiveError, 10)
+	s := r.newScanner(context.Background(), ReceiverOptions{}, []string{"67LDE", "6GN7J"}, errs)
+	x, y := s.byID["67LDE"], s.byID["6GN7J"]
+	// Nobody reads the channel for 67LDE.
+	for i := 0; i < readingBuffer; i++ {
+		x.readings <- nil
+	}
+	sim.Inject(SimPacket{Data: p1, RSSI: -70})
+	sim.Inject(SimPacket{Data: p3, RSSI: -60})
+	done := make(chan *Packet)
+	go func() { done <- s.hop(x, time.Second, y) }()
+	select {
+	case p := <-done:
+		if p == nil || p.TransmitterID != y.id {
+			t.Fatalf("hop() == %+v, want packet from %s", p, y.id)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("hop() blocked on unread channel")
+	}
+	if p := <-y.readings; p == nil || p.TransmitterID != y.id {
+		t.Errorf("reading for %s == %+v", y.id, p)
+	}
+	select {
+	case err := <-errs:
+		var e DroppedReadingError
+		if !errors.As(err, &e) || e.TransmitterID != x.id {
+			t.Errorf("error == %v, want DroppedReadingError for %s", err, x.id)
+		}
+	default:
+		t.Error("dropped reading not reported")
+	}
+}
+
+func TestSearchKeepsSyncedTransmitter(t *testing.T) {
+	r, sim := openSimulator(t)
+	opts := ReceiverOptions{
+		ReadingInterval: 400 * time.Millisecond,
+		ChannelInterval: 20 * time.Millisecond,
+		WakeupMargin:    50 * time.Millisecond,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// 67LDE transmits every reading interval; 6GN7J never does.
+	sim.Inject(SimPacket{Data: p1, RSSI: -70})
+	readings, errs := r.ReceiveTransmitters(ctx, []string{"67LDE", "6GN7J"}, &opts)
+	go func() {
+		for range errs {
+		}
+	}()
+	var first *Packet
+	select {
+	case first = <-readings["67LDE"]:
+	case <-time.After(time.Second):
+		t.Fatal("first reading not received")
+	}
+	if first == nil {
+		t.Fatal("first reading missed")
+	}
+	for i := 1; i <= 3; i++ {
+		time.AfterFunc(time.Until(first.Timestamp.Add(time.Duration(i)*opts.ReadingInterval)), func() {
+			sim.Inject(SimPacket{Data: p1, RSSI: -70})
+		})
+	}
+	for i := 1; i <= 3; i++ {
+		select {
+		case p := <-readings["67LDE"]:
+			// The simulator delivers packets on any channel, but a reading
+			// received after channel 0 means that a real radio would have
+			// been tuned elsewhere when it was sent on channel 0.
+			if p == nil || p.Channel != 0 {
+				t.Fatalf("reading %d == %+v, want packet on channel 0", i, p)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("reading %d not received", i)
+		}
+	}
+}
+
+func TestScannerCalibration(t *testing.T) {
+	r, sim := openSimulator(t)
+	s := r.newScanner(context.Background(), ReceiverOptions{}, []string{"67LDE", "6GN7J"}, nil)
+	x, y := s.byID["67LDE"], s.byID["6GN7J"]
+	sim.Inject(SimPacket{Data: p1, RSSI: -70, FreqEst: 0x04})
+	p := s.hop(y, time.Second, nil)
+	if p == nil || p.TransmitterID != x.id {
+		t.Fatalf("hop() == %+v, want packet from %s", p, x.id)
+	}
+	if !x.inSync || y.inSync {
+		t.Errorf("inSync == %v, %v; want true, false", x.inSync, y.inSync)
+	}
+	if x.offsets[0] != Channels[0].Offset+2 {
+		t.Errorf("%s offset == %02X, want %02X", x.id, x.offsets[0], Channels[0].Offset+2)
+	}
+	if y.offsets[0] != Channels[0].Offset {
+		t.Errorf("%s offset == %02X, want %02X", y.id, y.offsets[0], Channels[0].Offset)
+	}
+}
+
+func TestValidateTransmitterID(t *testing.T) {
+	cases := []struct {
+		id    string
+		valid bool
+	}{
+		{"67LDE", true},
+		{"6GN7J", true},
+		{"63KDG", true},
+		{"", false},
+		{"67LD", false},
+		{"67LDEF", false},
+		{"67LDV", false},
+		{"67lde", false},
+		{"6GN7O", false},
+	}
+	for _, c := range cases {
+		err := ValidateTransmitterID(c.id)
+		if (err == nil) != c.valid {
+			t.Errorf("ValidateTransmitterID(%q) == %v, want valid = %v", c.id, err, c.valid)
+		}
+	}
+}
+
+func TestReceiveReadingsInvalidOptions(t *testing.T) {
+	r, _ := openSimulator(t)
+	readings := r.ReceiveReadings(&ReceiverOptions{TransmitterID: "BOGUS"})
+	if r.Error() == nil {
+		t.Errorf("ReceiveReadings() with invalid transmitter ID succeeded")
+	}
+	if _, ok := <-readings; ok {
+		t.Errorf("readings channel not closed")
+	}
+}
+
+func TestReceiveTransmittersInvalid(t *testing.T) {
+	r, _ := openSimulator(t)
+	readings, errs := r.ReceiveTransmitters(context.Background(), []string{"67LDE", "BOGUS"}, nil)
+	if r.Error() == nil {
+		t.Errorf("ReceiveTransmitters() with invalid transmitter ID succeeded")
+	}
+	if len(readings) != 2 {
+		t.Errorf("ReceiveTransmitters() returned %d channels, want 2", len(readings))
+	}
+	for id, c := range readings {
+		if _, ok := <-c; ok {
+			t.Errorf("readings channel for %q not closed", id)
+		}
+	}
+	if _, ok := <-errs; ok {
+		t.Errorf("error channel not closed")
+	}
+}
+
+func TestReceiverOptionsDefaults(t *testing.T) {
+	opts := ReceiverOptions{ReadingInterval: time.Minute}
+	opts.setDefaults()
+	if opts.ReadingInterval != time.Minute {
+		t.Errorf("ReadingInterval == %v, want %v", opts.ReadingInterval, time.Minute)
+	}
+	if opts.ChannelInterval != defaultChannelInterval || opts.WakeupMargin != defaultWakeupMargin {
+		t.Errorf("setDefaults() == %+v", opts)
+	}
+	opts.Channels[0].Offset++
+	if Channels[0].Offset == opts.Channels[0].Offset {
+		t.Errorf("setDefaults() shares Channels table")
+	}
+}
+
+func TestSendReading(t *testing.T) {
+	r, s := openSimulator(t)
+	p := unmarshalPacket(time.Time{}, 0, p1, 0)
+	// SendReading supplies the usual port and device info.
+	p.Port, p.DeviceInfo = 0, 0
+	r.SendReading(p, &ReceiverOptions{ChannelInterval: 10 * time.Millisecond})
+	if r.Error() != nil {
+		t.Fatal(r.Error())
+	}
+	if p.Port != 0 || p.DeviceInfo != 0 {
+		t.Errorf("SendReading() modified its packet: %+v", *p)
+	}
+	sent := s.Transmitted()
+	if len(sent) != len(Channels) {
+		t.Fatalf("SendReading() sent %d packets, want %d", len(sent), len(Channels))
+	}
+	for _, data := range sent {
+		if !bytes.Equal(data, p1) {
+			t.Errorf("SendReading() sent % X, want % X", data, p1)
+		}
+	}
+}
+
+func TestReceiverStatus(t *testing.T) {
+	r, s := openSimulator(t)
+	s.Inject(SimPacket{Data: p3, RSSI: -60, FreqEst: 0x04})
+	ctx, cancel := context.WithCancel(context.Background())
+	readings := r.ReceiveReadingsContext(ctx, &ReceiverOptions{TransmitterID: "6GN7J"})
+	select {
+	case <-readings:
+	case <-time.After(time.Second):
+		t.Fatal("ReceiveReadings() timed out")
+	}
+	status := r.ReceiverStatus()
+	if !status.Running || status.LastRSSI != -60 || len(status.Transmitters) != 1 {
+		t.Fatalf("ReceiverStatus() == %+v", status)
+	}
+	x := status.Transmitters[0]
+	if x.TransmitterID != "6GN7J" || !x.InSync || x.Offsets[0] != Channels[0].Offset+2 {
+		t.Errorf("TransmitterStatus == %+v", x)
+	}
+	cancel()
+	for range readings {
+	}
+	if r.ReceiverStatus().Running {
+		t.Errorf("ReceiverStatus() reports running after cancellation")
+	}
+}
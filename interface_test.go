@@ -10,4 +10,8 @@ var (
 
 	// Ensure that *hwFlavor implements the radio.HardwareFlavor interface.
 	_ radio.HardwareFlavor = (*hwFlavor)(nil)
+
+	// Ensure that the SPI and simulated hardware implement the Hardware interface.
+	_ Hardware = (*spiHardware)(nil)
+	_ Hardware = (*Simulator)(nil)
 )
@@ -0,0 +1,93 @@
+package cc2500
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestMarshalUint16(t *testing.T) {
+	cases := []struct {
+		val uint16
+		rep []byte
+	}{
+		{0x1234, []byte{0x34, 0x12}},
+		{0, []byte{0, 0}},
+		{math.MaxUint16, []byte{0xFF, 0xFF}},
+	}
+	for _, c := range cases {
+		rep := marshalUint16(c.val)
+		if !bytes.Equal(rep, c.rep) {
+			t.Errorf("marshalUint16(%04X) == % X, want % X", c.val, rep, c.rep)
+		}
+	}
+}
+
+func TestMarshalUint32(t *testing.T) {
+	cases := []struct {
+		val uint32
+		rep []byte
+	}{
+		{0x12345678, []byte{0x78, 0x56, 0x34, 0x12}},
+		{0, []byte{0, 0, 0, 0}},
+		{math.MaxUint32, []byte{0xFF, 0xFF, 0xFF, 0xFF}},
+	}
+	for _, c := range cases {
+		rep := marshalUint32(c.val)
+		if !bytes.Equal(rep, c.rep) {
+			t.Errorf("marshalUint32(%08X) == % X, want % X", c.val, rep, c.rep)
+		}
+	}
+}
+
+func TestMarshalTransmitterID(t *testing.T) {
+	cases := []struct {
+		id  string
+		rep []byte
+	}{
+		{"63GEA", []byte{0xCA, 0xC1, 0x61, 0x00}},
+		{"64K6A", []byte{0xCA, 0x4C, 0x62, 0x00}},
+		{"67LDE", []byte{0xAE, 0xD1, 0x63, 0x00}},
+	}
+	for _, c := range cases {
+		rep, err := marshalTransmitterID(c.id)
+		if err != nil {
+			t.Errorf("marshalTransmitterID(%q) failed: %v", c.id, err)
+			continue
+		}
+		if !bytes.Equal(rep, c.rep) {
+			t.Errorf("marshalTransmitterID(%q) == % X, want % X", c.id, rep, c.rep)
+		}
+	}
+}
+
+func TestMarshalReadings(t *testing.T) {
+	cases := []struct {
+		f uint32
+		v []byte
+	}{
+		{156576, []byte{0xB8, 0xCD}},
+		{152448, []byte{0x39, 0x4D}},
+		{144192, []byte{0x59, 0x8D}},
+		{0, []byte{0x00, 0x00}},
+	}
+	for _, c := range cases {
+		v, err := marshalReading(c.f)
+		if err != nil {
+			t.Errorf("marshalReading(%d) failed: %v", c.f, err)
+			continue
+		}
+		if !bytes.Equal(v, c.v) {
+			t.Errorf("marshalReading(%d) == % X, want % X", c.f, v, c.v)
+		}
+	}
+	// Values with more than 13 significant bits are rounded down.
+	v, _ := marshalReading(144193)
+	if f := unmarshalReading(v); f != 144192 {
+		t.Errorf("unmarshalReading(marshalReading(144193)) == %d, want 144192", f)
+	}
+	_, err := marshalReading(maxReading + 1)
+	if err == nil {
+		t.Errorf("marshalReading(%d) succeeded", maxReading+1)
+	}
+}
@@ -0,0 +1,234 @@
+package cc2500
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Upper bounds of the RSSI histogram buckets, in dBm.
+var rssiBuckets = []int{-100, -90, -80, -70, -60, -50, -40}
+
+// Metrics accumulates statistics about G4 reception.
+// Set ReceiverOptions.Metrics to have the receiver update it,
+// and use WriteTo to export it in Prometheus text format.
+// It is safe for concurrent use.
+type Metrics struct {
+	mu        sync.Mutex
+	received  map[int]uint64 // valid packets, by channel
+	timeouts  map[int]uint64
+	crc       map[int]uint64 // hardware CRC failures
+	crc8      map[int]uint64
+	length    map[int]uint64
+	foreign   map[int]uint64 // packets from other transmitters
+	other     map[int]uint64 // other receive errors
+	overflows uint64
+	rssiCount []uint64 // by bucket, plus one for +Inf
+	rssiSum   int64
+	xmtrs     map[string]*transmitterMetrics
+}
+
+type transmitterMetrics struct {
+	inSync   bool
+	syncTime time.Duration
+	updated  time.Time
+	offsets  []uint8
+}
+
+// NewMetrics returns a new set of metrics with all counters zero.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		received:  make(map[int]uint64),
+		timeouts:  make(map[int]uint64),
+		crc:       make(map[int]uint64),
+		crc8:      make(map[int]uint64),
+		length:    make(map[int]uint64),
+		foreign:   make(map[int]uint64),
+		other:     make(map[int]uint64),
+		rssiCount: make([]uint64, len(rssiBuckets)+1),
+		xmtrs:     make(map[string]*transmitterMetrics),
+	}
+}
+
+// The methods below are called by the receiver and do nothing if m is nil.
+
+// start ensures that every channel appears in the per-channel counters.
+func (m *Metrics) start(numChannels int) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for n := 0; n < numChannels; n++ {
+		for _, c := range []map[int]uint64{m.received, m.timeouts, m.crc, m.crc8, m.length, m.foreign, m.other} {
+			c[n] += 0
+		}
+	}
+}
+
+// listened records the result of listening on channel n.
+func (m *Metrics) listened(n int, p *Packet, err error) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if p != nil {
+		m.received[n]++
+		i := sort.SearchInts(rssiBuckets, p.RSSI)
+		m.rssiCount[i]++
+		m.rssiSum += int64(p.RSSI)
+	}
+	if err == nil {
+		return
+	}
+	var (
+		crcErr    CRCError
+		crc8Err   CRC8Error
+		lengthErr LengthError
+		xmtrErr   TransmitterError
+	)
+	switch {
+	case err == ErrReceiveTimeout:
+		m.timeouts[n]++
+	case errors.As(err, &crcErr):
+		m.crc[n]++
+	case errors.As(err, &crc8Err):
+		m.crc8[n]++
+	case errors.As(err, &lengthErr):
+		m.length[n]++
+	case errors.As(err, &xmtrErr):
+		m.foreign[n]++
+	case err == ErrRXFIFOOverflow:
+		// Counted by rxOverflow.
+	default:
+		m.other[n]++
+	}
+}
+
+func (m *Metrics) rxOverflow() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.overflows++
+	m.mu.Unlock()
+}
+
+// update records the sync state and frequency offsets of each transmitter,
+// accumulating the time spent in sync since the previous update.
+func (m *Metrics) update(status ReceiverStatus, now time.Time) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, x := range status.Transmitters {
+		xm := m.xmtrs[x.TransmitterID]
+		if xm == nil {
+			xm = &transmitterMetrics{}
+			m.xmtrs[x.TransmitterID] = xm
+		} else if xm.inSync {
+			xm.syncTime += now.Sub(xm.updated)
+		}
+		xm.inSync = x.InSync && status.Running
+		xm.updated = now
+		xm.offsets = x.Offsets
+	}
+}
+
+// WriteTo writes the metrics to w in Prometheus text exposition format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	bw := bufio.NewWriter(w)
+	cw := &countingWriter{w: bw}
+	m.writeChannelCounter(cw, "cc2500_packets_received_total", "Valid G4 packets received, including those from other transmitters.", m.received)
+	m.writeChannelCounter(cw, "cc2500_receive_timeouts_total", "Receive timeouts.", m.timeouts)
+	m.writeChannelCounter(cw, "cc2500_crc_errors_total", "Packets that failed the hardware CRC check.", m.crc)
+	m.writeChannelCounter(cw, "cc2500_crc8_errors_total", "G4 packets with an incorrect CRC8 checksum.", m.crc8)
+	m.writeChannelCounter(cw, "cc2500_length_errors_total", "Packets with an unexpected length.", m.length)
+	m.writeChannelCounter(cw, "cc2500_foreign_packets_total", "Packets dropped because they came from another transmitter.", m.foreign)
+	m.writeChannelCounter(cw, "cc2500_receive_errors_total", "Other receive errors.", m.other)
+	cw.printf("# HELP cc2500_rx_fifo_overflows_total RX FIFO overflows.\n")
+	cw.printf("# TYPE cc2500_rx_fifo_overflows_total counter\n")
+	cw.printf("cc2500_rx_fifo_overflows_total %d\n", m.overflows)
+	cw.printf("# HELP cc2500_rssi_dbm RSSI of valid G4 packets.\n")
+	cw.printf("# TYPE cc2500_rssi_dbm histogram\n")
+	total := uint64(0)
+	for i, le := range rssiBuckets {
+		total += m.rssiCount[i]
+		cw.printf("cc2500_rssi_dbm_bucket{le=\"%d\"} %d\n", le, total)
+	}
+	total += m.rssiCount[len(rssiBuckets)]
+	cw.printf("cc2500_rssi_dbm_bucket{le=\"+Inf\"} %d\n", total)
+	cw.printf("cc2500_rssi_dbm_sum %d\n", m.rssiSum)
+	cw.printf("cc2500_rssi_dbm_count %d\n", total)
+	ids := make([]string, 0, len(m.xmtrs))
+	for id := range m.xmtrs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	cw.printf("# HELP cc2500_in_sync Whether the receiver is in sync with the transmitter.\n")
+	cw.printf("# TYPE cc2500_in_sync gauge\n")
+	for _, id := range ids {
+		cw.printf("cc2500_in_sync{transmitter=%q} %d\n", id, boolToInt(m.xmtrs[id].inSync))
+	}
+	cw.printf("# HELP cc2500_in_sync_seconds_total Time spent in sync with the transmitter.\n")
+	cw.printf("# TYPE cc2500_in_sync_seconds_total counter\n")
+	for _, id := range ids {
+		cw.printf("cc2500_in_sync_seconds_total{transmitter=%q} %g\n", id, m.xmtrs[id].syncTime.Seconds())
+	}
+	cw.printf("# HELP cc2500_fsctrl0_offset Current FSCTRL0 frequency offset register value (signed).\n")
+	cw.printf("# TYPE cc2500_fsctrl0_offset gauge\n")
+	for _, id := range ids {
+		for n, offset := range m.xmtrs[id].offsets {
+			cw.printf("cc2500_fsctrl0_offset{transmitter=%q,channel=\"%d\"} %d\n", id, n, int8(offset))
+		}
+	}
+	err := bw.Flush()
+	if cw.err == nil {
+		cw.err = err
+	}
+	return cw.n, cw.err
+}
+
+func (m *Metrics) writeChannelCounter(cw *countingWriter, name string, help string, counts map[int]uint64) {
+	cw.printf("# HELP %s %s\n", name, help)
+	cw.printf("# TYPE %s counter\n", name)
+	channels := make([]int, 0, len(counts))
+	for n := range counts {
+		channels = append(channels, n)
+	}
+	sort.Ints(channels)
+	for _, n := range channels {
+		cw.printf("%s{channel=\"%d\"} %d\n", name, n, counts[n])
+	}
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// countingWriter keeps track of the bytes written and the first error.
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) printf(format string, args ...interface{}) {
+	if cw.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(cw.w, format, args...)
+	cw.n += int64(n)
+	cw.err = err
+}
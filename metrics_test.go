@@ -0,0 +1,84 @@
+package cc2500
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	r, s := openSimulator(t)
+	badCRC8 := append([]byte(nil), p3...)
+	badCRC8[packetLength-1] ^= 0xFF
+	// Successive packets arrive on successive channels of the first hop.
+	s.Inject(SimPacket{Data: p1, RSSI: -80})
+	s.Inject(SimPacket{Data: p3, RSSI: -75, BadCRC: true})
+	s.Inject(SimPacket{Data: badCRC8, RSSI: -70})
+	// Too much of this packet arrives at once for the RX FIFO.
+	s.Inject(SimPacket{Data: make([]byte, 70), RSSI: -70, Burst: true})
+	s.Inject(SimPacket{Data: p3, RSSI: -65, FreqEst: 0x04})
+	m := NewMetrics()
+	ctx, cancel := context.WithCancel(context.Background())
+	readings, errs := r.ReceiveReadingsWithErrors(ctx, &ReceiverOptions{TransmitterID: "6GN7J", Metrics: m})
+	go func() {
+		for range errs {
+		}
+	}()
+	// The first hop ends with a missed reading, then p3 arrives on channel 0.
+	for p := (*Packet)(nil); p == nil; {
+		select {
+		case p = <-readings:
+		case <-time.After(time.Second):
+			t.Fatal("ReceiveReadings() timed out")
+		}
+	}
+	cancel()
+	for range readings {
+	}
+	var buf bytes.Buffer
+	n, err := m.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("WriteTo() returned %d, wrote %d bytes", n, buf.Len())
+	}
+	out := buf.String()
+	for _, line := range []string{
+		`cc2500_packets_received_total{channel="0"} 2`,
+		`cc2500_packets_received_total{channel="1"} 0`,
+		`cc2500_foreign_packets_total{channel="0"} 1`,
+		`cc2500_crc_errors_total{channel="1"} 1`,
+		`cc2500_crc8_errors_total{channel="2"} 1`,
+		`cc2500_rx_fifo_overflows_total 1`,
+		`cc2500_rssi_dbm_bucket{le="-90"} 0`,
+		`cc2500_rssi_dbm_bucket{le="-80"} 1`,
+		`cc2500_rssi_dbm_bucket{le="-60"} 2`,
+		`cc2500_rssi_dbm_sum -145`,
+		`cc2500_in_sync{transmitter="6GN7J"} 0`,
+		`cc2500_fsctrl0_offset{transmitter="6GN7J",channel="0"} -1`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("metrics output does not contain %q", line)
+		}
+	}
+	if t.Failed() {
+		t.Log(out)
+	}
+}
+
+func TestMetricsSyncTime(t *testing.T) {
+	m := NewMetrics()
+	t0 := time.Now()
+	status := ReceiverStatus{Running: true, Transmitters: []TransmitterStatus{{TransmitterID: "6GN7J", InSync: true}}}
+	m.update(status, t0)
+	m.update(status, t0.Add(time.Minute))
+	status.Transmitters[0].InSync = false
+	m.update(status, t0.Add(2*time.Minute))
+	m.update(status, t0.Add(3*time.Minute))
+	if d := m.xmtrs["6GN7J"].syncTime; d != 2*time.Minute {
+		t.Errorf("time in sync == %v, want %v", d, 2*time.Minute)
+	}
+}
@@ -0,0 +1,147 @@
+package cc2500
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Minimum time between writes of the state file.
+// Offsets learned in between are written when the receiver stops.
+const offsetSaveInterval = 10 * time.Minute
+
+// OffsetState contains the frequency offsets learned by a G4 receiver.
+// The offsets depend on the crystals of both the radio and the transmitter,
+// so they are only valid for the board on which they were learned.
+type OffsetState struct {
+	// Transmitters maps each transmitter ID to its channels,
+	// with the FSCTRL0 value learned for each one.
+	Transmitters map[string][]Channel
+}
+
+// ReadOffsetState reads the offsets saved in the given file.
+func ReadOffsetState(path string) (OffsetState, error) {
+	var state OffsetState
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return state, err
+	}
+	err = json.Unmarshal(data, &state)
+	if err != nil {
+		return state, fmt.Errorf("%s: %v", path, err)
+	}
+	return state, nil
+}
+
+// WriteOffsetState saves the offsets in the given file.
+// The file is replaced atomically, so a crash cannot leave it incomplete.
+func WriteOffsetState(path string, state OffsetState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(append(data, '\n'))
+	if err == nil {
+		err = tmp.Sync()
+	}
+	cerr := tmp.Close()
+	if err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// restoreOffsets loads the offsets for the scanner's transmitters
+// from the state file, if any. Saved offsets are ignored if they were
+// learned for a different set of channels.
+func (s *scanner) restoreOffsets() {
+	if s.opts.StateFile == "" {
+		return
+	}
+	state, err := ReadOffsetState(s.opts.StateFile)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	for _, x := range s.xmtrs {
+		saved := state.Transmitters[x.id]
+		if !sameChannels(saved, s.opts.Channels) {
+			continue
+		}
+		for i, c := range saved {
+			x.offsets[i] = c.Offset
+		}
+	}
+}
+
+func sameChannels(a, b []Channel) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Number != b[i].Number {
+			return false
+		}
+	}
+	return true
+}
+
+// offsetsChanged records a change in a transmitter's offsets,
+// saving them unless they were saved less than offsetSaveInterval ago.
+func (s *scanner) offsetsChanged() {
+	s.offsetsDirty = true
+	if time.Since(s.offsetsSaved) >= offsetSaveInterval {
+		s.flushOffsets()
+	}
+}
+
+// flushOffsets saves the offsets if they have changed since they were last saved.
+func (s *scanner) flushOffsets() {
+	if !s.offsetsDirty {
+		return
+	}
+	s.saveOffsets()
+	s.offsetsDirty = false
+	s.offsetsSaved = time.Now()
+}
+
+// saveOffsets writes the offsets of the scanner's transmitters
+// to the state file, if any.
+func (s *scanner) saveOffsets() {
+	if s.opts.StateFile == "" {
+		return
+	}
+	// Keep the offsets saved for other transmitters.
+	state, err := ReadOffsetState(s.opts.StateFile)
+	if err != nil || state.Transmitters == nil {
+		state = OffsetState{Transmitters: make(map[string][]Channel)}
+	}
+	for _, x := range s.xmtrs {
+		channels := make([]Channel, len(s.opts.Channels))
+		for i, c := range s.opts.Channels {
+			channels[i] = Channel{Number: c.Number, Offset: x.offsets[i]}
+		}
+		state.Transmitters[x.id] = channels
+	}
+	err = WriteOffsetState(s.opts.StateFile, state)
+	if err != nil {
+		log.Print(err)
+	}
+}
@@ -0,0 +1,119 @@
+package cc2500
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAdjustFrequency(t *testing.T) {
+	cases := []struct {
+		freqEst byte
+		offset  uint8
+	}{
+		{0x00, 0xFD},
+		{0x01, 0xFE},
+		{0x04, 0xFF},
+		{0x05, 0x00},
+		{0xFF, 0xFC},
+		{0xFA, 0xFA},
+		{0x10, 0x05},
+		{0x11, 0xFD}, // outlier
+		{0xE0, 0xFD}, // outlier
+	}
+	r, _ := openSimulator(t)
+	for _, c := range cases {
+		x := newTransmitter("6GN7J", Channels)
+		r.changeChannel(Channels[0], 0, x.offsets[0])
+		r.adjustFrequency(x, 0, c.freqEst)
+		if x.offsets[0] != c.offset {
+			t.Errorf("adjustFrequency(%02X) offset == %02X, want %02X", c.freqEst, x.offsets[0], c.offset)
+		}
+	}
+}
+
+func TestOffsetState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cc2500")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	stateFile := filepath.Join(dir, "offsets.json")
+	other := []Channel{{0, 0x01}, {100, 0x02}, {199, 0x03}, {209, 0x04}}
+	err = WriteOffsetState(stateFile, OffsetState{Transmitters: map[string][]Channel{"67LDE": other}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, sim := openSimulator(t)
+	sim.Inject(SimPacket{Data: p3, RSSI: -60, FreqEst: 0x04})
+	ctx, cancel := context.WithCancel(context.Background())
+	readings := r.ReceiveReadingsContext(ctx, &ReceiverOptions{TransmitterID: "6GN7J", StateFile: stateFile})
+	select {
+	case <-readings:
+	case <-time.After(time.Second):
+		t.Fatal("ReceiveReadings() timed out")
+	}
+	cancel()
+	for range readings {
+	}
+	state, err := ReadOffsetState(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := state.Transmitters["6GN7J"]
+	if len(saved) != len(Channels) || saved[0].Offset != Channels[0].Offset+2 || saved[1].Offset != Channels[1].Offset {
+		t.Errorf("saved offsets == %v", saved)
+	}
+	if !sameChannels(state.Transmitters["67LDE"], other) || state.Transmitters["67LDE"][3].Offset != 0x04 {
+		t.Errorf("offsets for other transmitter == %v, want %v", state.Transmitters["67LDE"], other)
+	}
+	// A new receiver starts with the saved offsets.
+	s := r.newScanner(context.Background(), ReceiverOptions{StateFile: stateFile}, []string{"6GN7J", "67LDE"}, nil)
+	if s.byID["6GN7J"].offsets[0] != Channels[0].Offset+2 {
+		t.Errorf("restored offset == %02X, want %02X", s.byID["6GN7J"].offsets[0], Channels[0].Offset+2)
+	}
+	if s.byID["67LDE"].offsets[2] != 0x03 {
+		t.Errorf("restored offset == %02X, want %02X", s.byID["67LDE"].offsets[2], 0x03)
+	}
+	// Offsets saved for different channels are ignored.
+	s = r.newScanner(context.Background(), ReceiverOptions{StateFile: stateFile, Channels: Channels[:2]}, []string{"6GN7J"}, nil)
+	if s.byID["6GN7J"].offsets[0] != Channels[0].Offset {
+		t.Errorf("offset restored for different channels")
+	}
+}
+
+func TestOffsetSaveInterval(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cc2500")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	stateFile := filepath.Join(dir, "offsets.json")
+	r, _ := openSimulator(t)
+	s := r.newScanner(context.Background(), ReceiverOptions{StateFile: stateFile}, []string{"6GN7J"}, nil)
+	x := s.byID["6GN7J"]
+	savedOffset := func() uint8 {
+		state, err := ReadOffsetState(stateFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return state.Transmitters["6GN7J"][0].Offset
+	}
+	x.offsets[0] = 0x10
+	s.offsetsChanged()
+	if savedOffset() != 0x10 {
+		t.Errorf("first offset change was not saved")
+	}
+	x.offsets[0] = 0x11
+	s.offsetsChanged()
+	if savedOffset() != 0x10 {
+		t.Errorf("offset change within %v was saved", offsetSaveInterval)
+	}
+	s.flushOffsets()
+	if savedOffset() != 0x11 {
+		t.Errorf("flushOffsets() did not save the latest offset")
+	}
+}
@@ -1,7 +1,9 @@
 package cc2500
 
 import (
+	"fmt"
 	"math/bits"
+	"strings"
 	"time"
 )
 
@@ -11,10 +13,16 @@ type Packet struct {
 	Channel       int
 	Data          []byte
 	TransmitterID string
+	Port          uint8
+	DeviceInfo    uint8
+	Sequence      uint8
 	Raw           uint32
 	Filtered      uint32
 	Battery       uint8
+	Unknown       uint8
 	RSSI          int
+	LQI           uint8 // link quality indicator
+	FreqEst       int32 // frequency offset estimate, in Hz
 }
 
 // Wire format of Dexcom G4 packet:
@@ -36,9 +44,13 @@ func unmarshalPacket(t time.Time, n int, data []byte, rssi int) *Packet {
 		Channel:       n,
 		Data:          data,
 		TransmitterID: unmarshalTransmitterID(data[4:8]),
+		Port:          data[8],
+		DeviceInfo:    data[9],
+		Sequence:      data[10],
 		Raw:           unmarshalReading(data[11:13]),
 		Filtered:      2 * unmarshalReading(data[13:15]),
 		Battery:       data[15],
+		Unknown:       data[16],
 		RSSI:          rssi,
 	}
 }
@@ -78,3 +90,73 @@ func unmarshalReading(v []byte) uint32 {
 	u := uint16(u0) | uint16(u1)<<8
 	return uint32(u&0x1FFF) << (u >> 13)
 }
+
+const (
+	defaultPort       = 0x3F
+	defaultDeviceInfo = 0x03
+)
+
+// MarshalBinary returns the wire format of the packet.
+// Readings that cannot be represented exactly are rounded down.
+func (p *Packet) MarshalBinary() ([]byte, error) {
+	id, err := marshalTransmitterID(p.TransmitterID)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := marshalReading(p.Raw)
+	if err != nil {
+		return nil, err
+	}
+	filtered, err := marshalReading(p.Filtered / 2)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, 0, packetLength)
+	data = append(data, 0xFF, 0xFF, 0xFF, 0xFF)
+	data = append(data, id...)
+	data = append(data, p.Port, p.DeviceInfo, p.Sequence)
+	data = append(data, raw...)
+	data = append(data, filtered...)
+	data = append(data, p.Battery, p.Unknown)
+	data = append(data, CRC8(data[11:]))
+	return data, nil
+}
+
+// Marshal a uint16 in little-endian order.
+func marshalUint16(n uint16) []byte {
+	return []byte{byte(n), byte(n >> 8)}
+}
+
+// Marshal a uint32 in little-endian order.
+func marshalUint32(n uint32) []byte {
+	return append(marshalUint16(uint16(n)), marshalUint16(uint16(n>>16))...)
+}
+
+func marshalTransmitterID(id string) ([]byte, error) {
+	err := ValidateTransmitterID(id)
+	if err != nil {
+		return nil, err
+	}
+	u := uint32(0)
+	for i := 0; i < 5; i++ {
+		n := strings.IndexByte(string(transmitterIDChar), id[i])
+		u |= uint32(n) << uint(20-5*i)
+	}
+	return marshalUint32(u), nil
+}
+
+const maxReading = 0x1FFF << 7
+
+// Marshal a uint32 as a 16-bit float (13-bit mantissa, 3-bit exponent),
+// using the smallest exponent that fits.
+func marshalReading(n uint32) ([]byte, error) {
+	if n > maxReading {
+		return nil, fmt.Errorf("reading %d is too large to encode", n)
+	}
+	exp := uint16(0)
+	for n>>exp > 0x1FFF {
+		exp++
+	}
+	u := exp<<13 | uint16(n>>exp)
+	return []byte{bits.Reverse8(byte(u)), bits.Reverse8(byte(u >> 8))}, nil
+}
@@ -1,6 +1,7 @@
 package cc2500
 
 import (
+	"bytes"
 	"reflect"
 	"testing"
 	"time"
@@ -22,6 +23,9 @@ func TestUnmarshalPacket(t *testing.T) {
 		{p1, Packet{
 			Data:          p1,
 			TransmitterID: "67LDE",
+			Port:          0x3F,
+			DeviceInfo:    0x03,
+			Sequence:      0x76,
 			Raw:           144192,
 			Filtered:      149760,
 			Battery:       213,
@@ -29,6 +33,9 @@ func TestUnmarshalPacket(t *testing.T) {
 		{p2, Packet{
 			Data:          p2,
 			TransmitterID: "67LDE",
+			Port:          0x3F,
+			DeviceInfo:    0x03,
+			Sequence:      0x6E,
 			Raw:           152448,
 			Filtered:      160288,
 			Battery:       213,
@@ -36,6 +43,9 @@ func TestUnmarshalPacket(t *testing.T) {
 		{p3, Packet{
 			Data:          p3,
 			TransmitterID: "6GN7J",
+			Port:          0x3F,
+			DeviceInfo:    0x03,
+			Sequence:      0xD6,
 			Raw:           198624,
 			Filtered:      202464,
 			Battery:       215,
@@ -43,6 +53,9 @@ func TestUnmarshalPacket(t *testing.T) {
 		{p4, Packet{
 			Data:          p4,
 			TransmitterID: "6GN7J",
+			Port:          0x3F,
+			DeviceInfo:    0x03,
+			Sequence:      0xDA,
 			Raw:           194560,
 			Filtered:      199488,
 			Battery:       215,
@@ -50,6 +63,9 @@ func TestUnmarshalPacket(t *testing.T) {
 		{p5, Packet{
 			Data:          p5,
 			TransmitterID: "63KDG",
+			Port:          0x3F,
+			DeviceInfo:    0x03,
+			Sequence:      0xAF,
 			Raw:           116864,
 			Filtered:      126160,
 			Battery:       217,
@@ -63,3 +79,31 @@ func TestUnmarshalPacket(t *testing.T) {
 		}
 	}
 }
+
+func TestMarshalPacket(t *testing.T) {
+	for _, data := range [][]byte{p1, p2, p3, p4, p5} {
+		p := unmarshalPacket(time.Time{}, 0, data, 0)
+		m, err := p.MarshalBinary()
+		if err != nil {
+			t.Errorf("MarshalBinary(%+v) failed: %v", *p, err)
+			continue
+		}
+		if !bytes.Equal(m, data) {
+			t.Errorf("MarshalBinary(%+v) == % X, want % X", *p, m, data)
+		}
+	}
+	// Zero port and device info bytes round-trip.
+	data := append([]byte{}, p1...)
+	data[8], data[9] = 0, 0
+	data[len(data)-1] = CRC8(data[11 : len(data)-1])
+	p := unmarshalPacket(time.Time{}, 0, data, 0)
+	m, err := p.MarshalBinary()
+	if err != nil || !bytes.Equal(m, data) {
+		t.Errorf("MarshalBinary(%+v) == % X, %v, want % X", *p, m, err, data)
+	}
+	p = &Packet{TransmitterID: "67LDV"}
+	_, err = p.MarshalBinary()
+	if err == nil {
+		t.Errorf("MarshalBinary(%+v) succeeded", *p)
+	}
+}
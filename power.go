@@ -0,0 +1,110 @@
+package cc2500
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	readyPoll    = 100 * time.Microsecond
+	readyTimeout = 50 * time.Millisecond
+)
+
+var (
+	// ErrChipNotReady indicates that the crystal oscillator
+	// did not stabilize after the chip was woken up.
+	ErrChipNotReady = errors.New("chip not ready after wakeup")
+
+	// ErrCalibrationTimeout indicates that frequency synthesizer
+	// calibration did not finish.
+	ErrCalibrationTimeout = errors.New("calibration timeout")
+)
+
+// Sleep saves the configuration registers and PATABLE and puts the chip
+// into SLEEP state, in which it draws the least current.
+// The chip does not retain the test registers or PATABLE (other than
+// its first entry) in SLEEP state, so Wake restores the saved values.
+func (r *Radio) Sleep() {
+	r.Strobe(SIDLE)
+	s := r.ReadSettings()
+	if r.Error() != nil {
+		return
+	}
+	r.saved = &s
+	r.Strobe(SPWD)
+	r.asleep = true
+}
+
+// CrystalOff turns off the crystal oscillator until Wake is called.
+// All registers are retained.
+func (r *Radio) CrystalOff() {
+	r.Strobe(SIDLE)
+	r.Strobe(SXOFF)
+	r.asleep = true
+}
+
+// Wake wakes the chip from SLEEP or crystal-off state,
+// waits for the crystal oscillator to stabilize,
+// restores the registers saved by Sleep,
+// and recalibrates the frequency synthesizer.
+// If this fails, the radio is still considered asleep,
+// so that a later call to Wake can try again.
+func (r *Radio) Wake() {
+	if !r.asleep {
+		return
+	}
+	r.awaitChipReady()
+	if r.Error() != nil {
+		return
+	}
+	if r.saved != nil {
+		r.WriteSettings(*r.saved)
+		if r.Error() != nil {
+			return
+		}
+	}
+	r.asleep = false
+	r.saved = nil
+	r.Calibrate()
+}
+
+// Asleep reports whether the chip has been put to sleep
+// or had its crystal oscillator turned off.
+func (r *Radio) Asleep() bool {
+	return r.asleep
+}
+
+// Accessing the chip pulls CSn low, which wakes it up.
+// The CHIP_RDYn bit of the status byte remains set
+// until the crystal oscillator is stable.
+func (r *Radio) awaitChipReady() {
+	deadline := time.Now().Add(readyTimeout)
+	for {
+		status := r.Strobe(SNOP)
+		if r.Error() != nil {
+			return
+		}
+		if status&CHIP_RDY == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			r.SetError(ErrChipNotReady)
+			return
+		}
+		time.Sleep(readyPoll)
+	}
+}
+
+// Calibrate calibrates the frequency synthesizer
+// and waits for the radio to return to IDLE state.
+func (r *Radio) Calibrate() {
+	r.Strobe(SCAL)
+	deadline := time.Now().Add(readyTimeout)
+	for r.Error() == nil && r.ReadState() != STATE_IDLE {
+		if time.Now().After(deadline) {
+			r.SetError(ErrCalibrationTimeout)
+			return
+		}
+		time.Sleep(readyPoll)
+	}
+}
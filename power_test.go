@@ -0,0 +1,107 @@
+package cc2500
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSleepWake(t *testing.T) {
+	r, _ := openSimulator(t)
+	config := r.ReadConfiguration()
+	config.TEST2 = TEST2_RX_LOW_DATA_RATE_MAGIC
+	config.TEST1 = TEST1_RX_LOW_DATA_RATE_MAGIC
+	r.WriteConfiguration(config)
+	table := []byte{0x00, 0xBB}
+	r.WritePATable(table)
+	r.Sleep()
+	if r.Error() != nil {
+		t.Fatal(r.Error())
+	}
+	if !r.Asleep() {
+		t.Errorf("Asleep() == false after Sleep()")
+	}
+	// Accessing the chip directly wakes it without restoring its registers.
+	if v := r.hw.ReadRegister(TEST2); v != ResetRFConfiguration.TEST2 {
+		t.Errorf("TEST2 after SLEEP == %02X, want reset value %02X", v, ResetRFConfiguration.TEST2)
+	}
+	r.Wake()
+	if r.Error() != nil {
+		t.Fatal(r.Error())
+	}
+	if r.Asleep() {
+		t.Errorf("Asleep() == true after Wake()")
+	}
+	if diff := r.VerifyConfiguration(config); diff != nil {
+		t.Errorf("configuration after Wake() differs:\n%v", diff)
+	}
+	if pa := r.ReadPATable(); !bytes.HasPrefix(pa, table) {
+		t.Errorf("PATABLE after Wake() == % X, want prefix % X", pa, table)
+	}
+	if r.State() != "IDLE" {
+		t.Errorf("state after Wake() == %s, want IDLE", r.State())
+	}
+}
+
+func TestWakeRetry(t *testing.T) {
+	r, s := openSimulator(t)
+	config := r.ReadConfiguration()
+	config.TEST2 = TEST2_RX_LOW_DATA_RATE_MAGIC
+	r.WriteConfiguration(config)
+	r.Sleep()
+	s.SetError(errors.New("SPI failure"))
+	r.Wake()
+	if r.Error() == nil {
+		t.Fatal("Wake() succeeded despite SPI failure")
+	}
+	if !r.Asleep() {
+		t.Errorf("Asleep() == false after failed Wake()")
+	}
+	s.SetError(nil)
+	r.SetError(nil)
+	r.Wake()
+	if r.Error() != nil {
+		t.Fatal(r.Error())
+	}
+	if r.Asleep() {
+		t.Errorf("Asleep() == true after Wake()")
+	}
+	if v := r.hw.ReadRegister(TEST2); v != TEST2_RX_LOW_DATA_RATE_MAGIC {
+		t.Errorf("TEST2 after retried Wake() == %02X, want %02X", v, TEST2_RX_LOW_DATA_RATE_MAGIC)
+	}
+}
+
+func TestCrystalOff(t *testing.T) {
+	r, _ := openSimulator(t)
+	before := r.ReadSettings()
+	r.CrystalOff()
+	r.Wake()
+	if r.Error() != nil {
+		t.Fatal(r.Error())
+	}
+	after := r.ReadSettings()
+	if after.Config.Diff(&before.Config) != nil || !bytes.Equal(after.PATable, before.PATable) {
+		t.Errorf("registers not retained with crystal off")
+	}
+}
+
+func TestScannerSleep(t *testing.T) {
+	r, _ := openSimulator(t)
+	s := r.newScanner(context.Background(), ReceiverOptions{Sleep: true}, []string{"67LDE"}, nil)
+	before := r.ReadSettings()
+	start := time.Now()
+	if !s.sleep(start.Add(20 * time.Millisecond)) {
+		t.Fatal("sleep() returned false")
+	}
+	if time.Since(start) < 20*time.Millisecond {
+		t.Errorf("sleep() returned after %v", time.Since(start))
+	}
+	if r.Asleep() || r.Error() != nil {
+		t.Errorf("after sleep(): Asleep() == %v, Error() == %v", r.Asleep(), r.Error())
+	}
+	if diff := r.VerifyConfiguration(&before.Config); diff != nil {
+		t.Errorf("configuration after sleep() differs:\n%v", diff)
+	}
+}
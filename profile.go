@@ -0,0 +1,410 @@
+package cc2500
+
+import (
+	"fmt"
+)
+
+// Modulation is a modulation format (MDMCFG2.MOD_FORMAT).
+type Modulation byte
+
+// Modulation formats supported by the CC2500.
+const (
+	FSK2 Modulation = MDMCFG2_MOD_FORMAT_2_FSK >> 4
+	GFSK Modulation = MDMCFG2_MOD_FORMAT_GFSK >> 4
+	OOK  Modulation = MDMCFG2_MOD_FORMAT_ASK_OOK >> 4
+	MSK  Modulation = MDMCFG2_MOD_FORMAT_MSK >> 4
+)
+
+func (m Modulation) String() string {
+	if int(m) >= len(modFormat) || modFormat[m] == "-" {
+		return fmt.Sprintf("Modulation(%d)", m)
+	}
+	return modFormat[m]
+}
+
+// SyncMode is a sync word qualifier mode (MDMCFG2.SYNC_MODE).
+type SyncMode byte
+
+// Sync word qualifier modes.
+// The CarrierSense variants also require the signal to be above
+// the carrier sense threshold.
+const (
+	SyncNone SyncMode = iota
+	Sync15of16
+	Sync16of16
+	Sync30of32
+	SyncNoneCarrierSense
+	Sync15of16CarrierSense
+	Sync16of16CarrierSense
+	Sync30of32CarrierSense
+)
+
+func (m SyncMode) String() string {
+	if int(m) >= len(syncMode) {
+		return fmt.Sprintf("SyncMode(%d)", m)
+	}
+	return syncMode[m]
+}
+
+// LengthMode is a packet length configuration (PKTCTRL0.LENGTH_CONFIG).
+type LengthMode byte
+
+// Packet length modes.
+const (
+	FixedLength    LengthMode = PKTCTRL0_LENGTH_CONFIG_FIXED
+	VariableLength LengthMode = PKTCTRL0_LENGTH_CONFIG_VARIABLE
+	InfiniteLength LengthMode = PKTCTRL0_LENGTH_CONFIG_INFINITE
+)
+
+// AddressCheck is an address filtering mode (PKTCTRL1.ADR_CHK).
+type AddressCheck byte
+
+// Address filtering modes.
+const (
+	AddressCheckNone          AddressCheck = PKTCTRL1_ADR_CHK_NONE
+	AddressCheckNoBroadcast   AddressCheck = PKTCTRL1_ADR_CHK_NO_BROADCAST
+	AddressCheck00Broadcast   AddressCheck = PKTCTRL1_ADR_CHK_00_BROADCAST
+	AddressCheck00FFBroadcast AddressCheck = PKTCTRL1_ADR_CHK_00_FF_BROADCAST
+)
+
+// Profile describes the modulation and packet format of a radio link.
+// Frequencies are in Hertz and data rates in Baud.
+// Values that cannot be represented exactly are rounded
+// to the closest available setting.
+type Profile struct {
+	Frequency      uint32 // frequency of channel 0
+	ChannelSpacing uint32
+	IF             uint32 // intermediate frequency; 0 means the reset value
+	Bandwidth      uint32 // receiver channel filter bandwidth
+
+	Modulation Modulation
+	DataRate   uint32
+	Deviation  uint32 // for MSK, see the DEVIATN description in the data sheet
+	Manchester bool
+	FEC        bool // forward error correction (fixed-length packets only)
+	Whitening  bool
+
+	SyncWord uint16
+	SyncMode SyncMode
+	Preamble int // minimum number of preamble bytes to send: 2, 3, 4, 6, 8, 12, 16, or 24
+
+	Length       LengthMode
+	PacketLength byte // packet length if fixed, otherwise maximum length
+	CRC          bool
+	AddressCheck AddressCheck
+	Address      byte
+
+	Power byte // PATABLE value used for transmitting
+
+	CCAMode CCAMode
+	// Carrier sense thresholds: absolute in dB relative to the AGC's
+	// MAGN_TARGET (-7 to 7, or CarrierSenseDisabled), and relative.
+	CarrierSense         int
+	RelativeCarrierSense RelativeThreshold
+
+	// Tuning, if not zero, overrides the settings recommended
+	// for the data rate and modulation.
+	Tuning Tuning
+}
+
+// Tuning holds the frequency offset compensation, bit synchronization,
+// AGC, front end, and synthesizer calibration settings.
+// They are not derived from the link parameters in the data sheet,
+// but chosen from sets recommended by SmartRF Studio.
+type Tuning struct {
+	FOCCFG   byte
+	BSCFG    byte
+	AGCCTRL2 byte
+	AGCCTRL0 byte
+	FREND1   byte
+	FSCAL3   byte
+	FSCAL2   byte
+	FSCAL1   byte
+	FSCAL0   byte
+}
+
+// RecommendedTuning returns the SmartRF Studio settings
+// for the given data rate and modulation.
+func RecommendedTuning(dataRate uint32, m Modulation) Tuning {
+	t := Tuning{
+		FSCAL2: 0x0A,
+		FSCAL1: 0x00,
+		FSCAL0: 0x11,
+	}
+	if dataRate <= 100000 {
+		t.FOCCFG = FOCCFG_FOC_PRE_K_3K |
+			FOCCFG_FOC_POST_K_PRE_K_OVER_2 |
+			FOCCFG_FOC_LIMIT_BW_OVER_4
+		t.BSCFG = BSCFG_BS_PRE_KI_2KI |
+			BSCFG_BS_PRE_KP_3KP |
+			BSCFG_BS_POST_KI_PRE_KI_OVER_2 |
+			BSCFG_BS_POST_KP_KP |
+			BSCFG_BS_LIMIT_0
+		t.AGCCTRL2 = AGCCTRL2_MAX_DVGA_GAIN_BUT_1 |
+			AGCCTRL2_MAX_LNA_GAIN_0 |
+			AGCCTRL2_MAGN_TARGET_33dB
+		t.AGCCTRL0 = AGCCTRL0_HYST_LEVEL_MEDIUM |
+			AGCCTRL0_WAIT_TIME_16 |
+			AGCCTRL0_AGC_FREEZE_NORMAL |
+			AGCCTRL0_FILTER_LENGTH_16
+		t.FREND1 = 1<<FREND1_LNA_CURRENT_SHIFT |
+			1<<FREND1_LNA2MIX_CURRENT_SHIFT |
+			1<<FREND1_LODIV_BUF_CURRENT_RX_SHIFT |
+			2<<FREND1_MIX_CURRENT_SHIFT
+		t.FSCAL3 = 2<<6 | 2<<4 | 0x09
+	} else {
+		t.FOCCFG = FOCCFG_FOC_PRE_K_4K |
+			FOCCFG_FOC_POST_K_PRE_K_OVER_2 |
+			FOCCFG_FOC_LIMIT_BW_OVER_8
+		t.BSCFG = BSCFG_BS_PRE_KI_1KI |
+			BSCFG_BS_PRE_KP_2KP |
+			BSCFG_BS_POST_KI_PRE_KI_OVER_2 |
+			BSCFG_BS_POST_KP_KP |
+			BSCFG_BS_LIMIT_0
+		t.AGCCTRL2 = AGCCTRL2_MAX_DVGA_GAIN_BUT_3 |
+			AGCCTRL2_MAX_LNA_GAIN_0 |
+			AGCCTRL2_MAGN_TARGET_42dB
+		t.AGCCTRL0 = AGCCTRL0_HYST_LEVEL_MEDIUM |
+			AGCCTRL0_WAIT_TIME_32 |
+			AGCCTRL0_AGC_FREEZE_NORMAL |
+			AGCCTRL0_FILTER_LENGTH_32
+		if dataRate > 250000 {
+			t.AGCCTRL0 = t.AGCCTRL0&^AGCCTRL0_FILTER_LENGTH_64 | AGCCTRL0_FILTER_LENGTH_8
+		}
+		t.FREND1 = 2<<FREND1_LNA_CURRENT_SHIFT |
+			3<<FREND1_LNA2MIX_CURRENT_SHIFT |
+			1<<FREND1_LODIV_BUF_CURRENT_RX_SHIFT |
+			2<<FREND1_MIX_CURRENT_SHIFT
+		t.FSCAL3 = 3<<6 | 2<<4 | 0x0A
+	}
+	if m == OOK {
+		// Use all of the DVGA gain and a lower target amplitude
+		// so that the AGC does not track the on-off keying.
+		t.AGCCTRL2 = AGCCTRL2_MAX_DVGA_GAIN_ALL |
+			AGCCTRL2_MAX_LNA_GAIN_0 |
+			AGCCTRL2_MAGN_TARGET_33dB
+	}
+	return t
+}
+
+// G4Tuning holds the settings used by the Dexcom G4 receiver.
+var G4Tuning = Tuning{
+	FOCCFG: FOCCFG_FOC_PRE_K_2K |
+		FOCCFG_FOC_POST_K_PRE_K |
+		FOCCFG_FOC_LIMIT_BW_OVER_4,
+	BSCFG: BSCFG_BS_PRE_KI_2KI |
+		BSCFG_BS_PRE_KP_3KP |
+		BSCFG_BS_POST_KI_PRE_KI_OVER_2 |
+		BSCFG_BS_POST_KP_PRE_KP |
+		BSCFG_BS_LIMIT_0,
+	AGCCTRL2: AGCCTRL2_MAX_DVGA_GAIN_BUT_1 |
+		AGCCTRL2_MAX_LNA_GAIN_0 |
+		AGCCTRL2_MAGN_TARGET_36dB,
+	AGCCTRL0: AGCCTRL0_HYST_LEVEL_MEDIUM |
+		AGCCTRL0_WAIT_TIME_32 |
+		AGCCTRL0_AGC_FREEZE_NORMAL |
+		AGCCTRL0_FILTER_LENGTH_32,
+	FREND1: 2<<FREND1_LNA_CURRENT_SHIFT |
+		3<<FREND1_LNA2MIX_CURRENT_SHIFT |
+		1<<FREND1_LODIV_BUF_CURRENT_RX_SHIFT |
+		2<<FREND1_MIX_CURRENT_SHIFT,
+	FSCAL3: 2<<6 | 2<<4 | 0x09,
+	FSCAL2: 0x0A,
+	FSCAL1: 0x00,
+	FSCAL0: 0x20,
+}
+
+// G4Profile is the profile used by the Dexcom G4 transmitter.
+// See data sheet section 13 and Dexcom's FCC filing at
+// https://apps.fcc.gov/eas/GetApplicationAttachment.html?id=1373548
+var G4Profile = Profile{
+	Frequency:      BaseFrequency,
+	ChannelSpacing: 249938,
+	IF:             228515, // FSCTRL1 = 0x09
+	Bandwidth:      325000, // CHANBW_E = 1, CHANBW_M = 1
+	Modulation:     MSK,
+	DataRate:       49987, // DRATE_E = 10, DRATE_M = 248
+	Deviation:      25390, // DEVIATN = 0x40
+	SyncWord:       0xD391,
+	SyncMode:       Sync30of32,
+	Preamble:       2,
+	Length:         VariableLength,
+	PacketLength:   0xFF,
+	CRC:            true,
+	Power:          0xBB, // see section 24 of the data sheet
+	Tuning:         G4Tuning,
+}
+
+// ProfileError indicates a profile that cannot be configured.
+type ProfileError struct {
+	Field  string
+	Reason string
+}
+
+func (e ProfileError) Error() string {
+	return fmt.Sprintf("invalid radio profile: %s %s", e.Field, e.Reason)
+}
+
+// Configuration computes the register settings for the profile.
+func (p Profile) Configuration() (RFConfiguration, error) {
+	rf := ResetRFConfiguration
+	switch p.Modulation {
+	case FSK2, GFSK, OOK, MSK:
+	default:
+		return rf, ProfileError{"Modulation", fmt.Sprintf("%d is not supported", p.Modulation)}
+	}
+	if p.SyncMode > Sync30of32CarrierSense {
+		return rf, ProfileError{"SyncMode", fmt.Sprintf("%d is not supported", p.SyncMode)}
+	}
+	if p.Length > InfiniteLength {
+		return rf, ProfileError{"Length", fmt.Sprintf("%d is not supported", p.Length)}
+	}
+	if p.AddressCheck > AddressCheck00FFBroadcast {
+		return rf, ProfileError{"AddressCheck", fmt.Sprintf("%d is not supported", p.AddressCheck)}
+	}
+	preamble := -1
+	for i, n := range numPreamble {
+		if int(n) == p.Preamble {
+			preamble = i
+		}
+	}
+	if preamble < 0 {
+		return rf, ProfileError{"Preamble", fmt.Sprintf("length %d is not supported", p.Preamble)}
+	}
+	if p.FEC && p.Length != FixedLength {
+		return rf, ProfileError{"FEC", "requires fixed-length packets"}
+	}
+	if p.Manchester && p.Modulation == MSK {
+		return rf, ProfileError{"Manchester", "encoding cannot be used with MSK"}
+	}
+	if p.DataRate == 0 || p.DataRate > 500000 {
+		return rf, ProfileError{"DataRate", fmt.Sprintf("%d is out of range", p.DataRate)}
+	}
+	if p.Bandwidth == 0 {
+		return rf, ProfileError{"Bandwidth", "must be specified"}
+	}
+
+	// Asserts when sync word has been sent/received,
+	// and de-asserts at the end of the packet.
+	rf.IOCFG0 = 0x06
+
+	rf.SYNC1 = byte(p.SyncWord >> 8)
+	rf.SYNC0 = byte(p.SyncWord)
+
+	rf.PKTLEN = p.PacketLength
+	rf.PKTCTRL1 = PKTCTRL1_APPEND_STATUS | byte(p.AddressCheck)
+	rf.PKTCTRL0 = byte(p.Length)
+	if p.CRC {
+		rf.PKTCTRL0 |= PKTCTRL0_CRC_EN
+	}
+	if p.Whitening {
+		rf.PKTCTRL0 |= PKTCTRL0_WHITE_DATA
+	}
+	rf.ADDR = p.Address
+
+	if p.IF != 0 {
+		rf.FSCTRL1 = byte((uint64(p.IF)<<10 + FXOSC/2) / FXOSC)
+	}
+
+	fb := frequencyToRegisters(p.Frequency)
+	rf.FREQ2 = fb[0]
+	rf.FREQ1 = fb[1]
+	rf.FREQ0 = fb[2]
+
+	bwE, bwM, _ := BandwidthRegisters(p.Bandwidth)
+	drE, drM, _ := DataRateRegisters(p.DataRate)
+	rf.MDMCFG4 = bwE<<MDMCFG4_CHANBW_E_SHIFT |
+		bwM<<MDMCFG4_CHANBW_M_SHIFT |
+		drE<<MDMCFG4_DRATE_E_SHIFT
+	rf.MDMCFG3 = drM << MDMCFG3_DRATE_M_SHIFT
+
+	rf.MDMCFG2 = MDMCFG2_DEM_DCFILT_ON |
+		byte(p.Modulation)<<4 |
+		byte(p.SyncMode)
+	if p.Manchester {
+		rf.MDMCFG2 |= MDMCFG2_MANCHESTER_EN
+	}
+
+	csE, csM, _ := ChannelSpacingRegisters(p.ChannelSpacing)
+	rf.MDMCFG1 = byte(preamble)<<4 | csE<<MDMCFG1_CHANSPC_E_SHIFT
+	if p.FEC {
+		rf.MDMCFG1 |= MDMCFG1_FEC_EN
+	}
+	rf.MDMCFG0 = csM << MDMCFG0_CHANSPC_M_SHIFT
+
+	devE, devM, _ := DeviationRegisters(p.Deviation)
+	rf.DEVIATN = devE<<DEVIATN_DEVIATION_E_SHIFT | devM<<DEVIATN_DEVIATION_M_SHIFT
+
+	rf.MCSM2 = MCSM2_RX_TIME_END_OF_PACKET
+
+	if p.CCAMode > CCARSSIBelowUnlessReceiving {
+		return rf, ProfileError{"CCAMode", fmt.Sprintf("%d is not supported", p.CCAMode)}
+	}
+	rf.MCSM1 = byte(p.CCAMode)<<MCSM1_CCA_MODE_SHIFT |
+		MCSM1_RXOFF_MODE_IDLE |
+		MCSM1_TXOFF_MODE_IDLE
+
+	rf.MCSM0 = MCSM0_FS_AUTOCAL_FROM_IDLE
+
+	t := p.Tuning
+	if t == (Tuning{}) {
+		t = RecommendedTuning(p.DataRate, p.Modulation)
+	}
+	rf.FOCCFG = t.FOCCFG
+	rf.BSCFG = t.BSCFG
+	rf.AGCCTRL2 = t.AGCCTRL2
+	rf.AGCCTRL0 = t.AGCCTRL0
+	rf.FREND1 = t.FREND1
+	rf.FSCAL3 = t.FSCAL3
+	rf.FSCAL2 = t.FSCAL2
+	rf.FSCAL1 = t.FSCAL1
+	rf.FSCAL0 = t.FSCAL0
+
+	cs, err := carrierSenseRegister(p.CarrierSense, p.RelativeCarrierSense)
+	if err != nil {
+		return rf, ProfileError{"CarrierSense", err.Error()}
+	}
+	rf.AGCCTRL1 = AGCCTRL1_AGC_LNA_PRIORITY_0 | cs
+
+	// For OOK, use PA_TABLE 1 for transmitting '1'
+	// (PA_TABLE 0 is always used for '0').
+	rf.FREND0 = 1 << FREND0_LODIV_BUF_CURRENT_TX_SHIFT
+	if p.Modulation == OOK {
+		rf.FREND0 |= 1 << FREND0_PA_POWER_SHIFT
+	}
+
+	// The data sheet recommends different TEST2 and TEST1 values
+	// for data rates up to 100 kBaud.
+	if p.DataRate <= 100000 {
+		rf.TEST2 = TEST2_RX_LOW_DATA_RATE_MAGIC
+		rf.TEST1 = TEST1_RX_LOW_DATA_RATE_MAGIC
+	}
+	rf.TEST0 = 2<<2 | 1<<1 | 1<<0
+
+	return rf, nil
+}
+
+// PATable returns the power amplifier table entries for the profile.
+func (p Profile) PATable() []byte {
+	if p.Modulation == OOK {
+		return []byte{0x00, p.Power}
+	}
+	return []byte{p.Power}
+}
+
+// InitProfile resets the radio and configures it according to the given profile.
+func (r *Radio) InitProfile(p Profile) {
+	r.Reset()
+	r.WriteProfile(p)
+}
+
+// WriteProfile configures the radio according to the given profile.
+func (r *Radio) WriteProfile(p Profile) {
+	rf, err := p.Configuration()
+	if err != nil {
+		r.SetError(err)
+		return
+	}
+	r.WriteConfiguration(&rf)
+	r.WritePATable(p.PATable())
+}
@@ -0,0 +1,163 @@
+package cc2500
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestG4Profile(t *testing.T) {
+	// Register values used for the G4 before profiles were introduced.
+	want := []byte{
+		0x29, 0x2E, 0x06, 0x07, 0xD3, 0x91, 0xFF, 0x04,
+		0x05, 0x00, 0x00, 0x09, 0x00, 0x5D, 0x44, 0xEC,
+		0x5A, 0xF8, 0x73, 0x03, 0x3B, 0x40, 0x07, 0x00,
+		0x10, 0x0A, 0x68, 0x44, 0x00, 0xB2, 0x87, 0x6B,
+		0xF8, 0xB6, 0x10, 0xA9, 0x0A, 0x00, 0x20, 0x41,
+		0x00, 0x59, 0x7F, 0x3F, 0x81, 0x35, 0x0B,
+	}
+	rf, err := G4Profile.Configuration()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rf.Bytes(), want) {
+		t.Errorf("G4Profile.Configuration() == % X, want % X", rf.Bytes(), want)
+	}
+	if !bytes.Equal(G4Profile.PATable(), []byte{0xBB}) {
+		t.Errorf("G4Profile.PATable() == % X, want BB", G4Profile.PATable())
+	}
+}
+
+var testProfile = Profile{
+	Frequency:      2440000000,
+	ChannelSpacing: 200000,
+	Bandwidth:      203125,
+	Modulation:     GFSK,
+	DataRate:       38400,
+	Deviation:      20000,
+	Whitening:      true,
+	SyncWord:       0xBEEF,
+	SyncMode:       Sync16of16,
+	Preamble:       4,
+	Length:         FixedLength,
+	PacketLength:   8,
+	CRC:            true,
+	Power:          0xFE,
+}
+
+func TestProfileConfiguration(t *testing.T) {
+	rf, err := testProfile.Configuration()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checks := []struct {
+		name       string
+		have, want byte
+	}{
+		{"SYNC1", rf.SYNC1, 0xBE},
+		{"SYNC0", rf.SYNC0, 0xEF},
+		{"PKTLEN", rf.PKTLEN, 8},
+		{"PKTCTRL0", rf.PKTCTRL0, PKTCTRL0_WHITE_DATA | PKTCTRL0_CRC_EN | PKTCTRL0_LENGTH_CONFIG_FIXED},
+		{"MDMCFG2", rf.MDMCFG2, MDMCFG2_MOD_FORMAT_GFSK | MDMCFG2_SYNC_MODE_16_16},
+		{"MDMCFG1", rf.MDMCFG1 & MDMCFG1_NUM_PREAMBLE_MASK, MDMCFG1_NUM_PREAMBLE_4},
+		{"FSCTRL1", rf.FSCTRL1, ResetRFConfiguration.FSCTRL1},
+		{"FREND0", rf.FREND0 & FREND0_PA_POWER_MASK, 0},
+	}
+	for _, c := range checks {
+		if c.have != c.want {
+			t.Errorf("%s == %02X, want %02X", c.name, c.have, c.want)
+		}
+	}
+	ook := testProfile
+	ook.Modulation = OOK
+	if !bytes.Equal(ook.PATable(), []byte{0x00, 0xFE}) {
+		t.Errorf("OOK PATable() == % X, want 00 FE", ook.PATable())
+	}
+}
+
+func TestRecommendedTuning(t *testing.T) {
+	cases := []struct {
+		dataRate uint32
+		m        Modulation
+		want     []byte // FOCCFG, BSCFG, AGCCTRL2, AGCCTRL0, FREND1, FSCAL3..0
+	}{
+		{10000, FSK2, []byte{0x16, 0x6C, 0x43, 0x91, 0x56, 0xA9, 0x0A, 0x00, 0x11}},
+		{38400, GFSK, []byte{0x16, 0x6C, 0x43, 0x91, 0x56, 0xA9, 0x0A, 0x00, 0x11}},
+		{250000, MSK, []byte{0x1D, 0x1C, 0xC7, 0xB2, 0xB6, 0xEA, 0x0A, 0x00, 0x11}},
+		{500000, MSK, []byte{0x1D, 0x1C, 0xC7, 0xB0, 0xB6, 0xEA, 0x0A, 0x00, 0x11}},
+		{2400, OOK, []byte{0x16, 0x6C, 0x03, 0x91, 0x56, 0xA9, 0x0A, 0x00, 0x11}},
+	}
+	for _, c := range cases {
+		p := testProfile
+		p.DataRate = c.dataRate
+		p.Modulation = c.m
+		rf, err := p.Configuration()
+		if err != nil {
+			t.Fatal(err)
+		}
+		have := []byte{rf.FOCCFG, rf.BSCFG, rf.AGCCTRL2, rf.AGCCTRL0, rf.FREND1, rf.FSCAL3, rf.FSCAL2, rf.FSCAL1, rf.FSCAL0}
+		if !bytes.Equal(have, c.want) {
+			t.Errorf("%v at %d Baud: tuning registers == % X, want % X", c.m, c.dataRate, have, c.want)
+		}
+	}
+	// Explicit settings override the recommended ones.
+	p := testProfile
+	p.Tuning = G4Tuning
+	rf, err := p.Configuration()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rf.FOCCFG != G4Tuning.FOCCFG || rf.FSCAL0 != G4Tuning.FSCAL0 {
+		t.Errorf("Configuration() ignored explicit tuning")
+	}
+}
+
+func TestProfileErrors(t *testing.T) {
+	cases := []func(p *Profile){
+		func(p *Profile) { p.Modulation = 2 },
+		func(p *Profile) { p.Preamble = 5 },
+		func(p *Profile) { p.Length = VariableLength; p.FEC = true },
+		func(p *Profile) { p.Modulation = MSK; p.Manchester = true },
+		func(p *Profile) { p.DataRate = 0 },
+		func(p *Profile) { p.Bandwidth = 0 },
+		func(p *Profile) { p.SyncMode = 8 },
+	}
+	for i, change := range cases {
+		p := testProfile
+		change(&p)
+		_, err := p.Configuration()
+		if _, ok := err.(ProfileError); !ok {
+			t.Errorf("case %d: Configuration() error == %v, want ProfileError", i, err)
+		}
+	}
+}
+
+func TestFixedLengthProfile(t *testing.T) {
+	r, s := openSimulator(t)
+	r.InitProfile(testProfile)
+	if r.Error() != nil {
+		t.Fatal(r.Error())
+	}
+	packet := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	r.Send(packet)
+	if r.Error() != nil {
+		t.Fatal(r.Error())
+	}
+	sent := s.Transmitted()
+	if len(sent) != 1 || !bytes.Equal(sent[0], packet) {
+		t.Errorf("Transmitted() == % X, want [% X]", sent, packet)
+	}
+	r.Send(packet[:4])
+	if r.Error() == nil {
+		t.Errorf("Send() of short fixed-length packet succeeded")
+	}
+	r.SetError(nil)
+	s.Inject(SimPacket{Data: packet, RSSI: -50})
+	data, rssi := r.Receive(time.Second)
+	if r.Error() != nil {
+		t.Fatal(r.Error())
+	}
+	if !bytes.Equal(data, packet) || rssi != -50 {
+		t.Errorf("Receive() == % X, %d; want % X, -50", data, rssi, packet)
+	}
+}
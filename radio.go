@@ -1,6 +1,7 @@
 package cc2500
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -13,6 +14,7 @@ const (
 	fifoSize     = 64
 	minRSSI      = math.MinInt8
 	deassertPoll = 2 * time.Millisecond
+	cancelPoll   = 100 * time.Millisecond
 )
 
 func init() {
@@ -24,6 +26,34 @@ func init() {
 // ErrReceiveTimeout indicates that a Receive operation timed out.
 var ErrReceiveTimeout = errors.New("receive timeout")
 
+// ErrTransmitTimeout indicates that a packet was not transmitted
+// within the time needed to send the contents of the TX FIFO.
+var ErrTransmitTimeout = errors.New("transmit timeout")
+
+// Time allowed for TX to finish beyond the air time of a full TX FIFO.
+const txSlack = 20 * time.Millisecond
+
+// CRCError indicates a packet that failed the hardware CRC check.
+type CRCError struct {
+	Data []byte // contents of RX FIFO
+	RSSI int
+}
+
+func (e CRCError) Error() string {
+	return fmt.Sprintf("invalid CRC: % X (RSSI %d)", e.Data, e.RSSI)
+}
+
+// LengthError indicates a packet with an unexpected length.
+type LengthError struct {
+	Data     []byte
+	Expected int
+	RSSI     int
+}
+
+func (e LengthError) Error() string {
+	return fmt.Sprintf("unexpected %d-byte packet (want %d): % X (RSSI %d)", len(e.Data), e.Expected, e.Data, e.RSSI)
+}
+
 // Receive listens with the given timeout for an incoming packet.
 // It returns the packet and the associated RSSI.
 // Packet layout in RX FIFO:
@@ -33,82 +63,209 @@ var ErrReceiveTimeout = errors.New("receive timeout")
 //	n+2: CRC OK and LQI
 // 2-byte CRC following packet body is checked and stripped in hardware.
 func (r *Radio) Receive(timeout time.Duration) ([]byte, int) {
+	data, rssi, _ := r.ReceiveContext(context.Background(), timeout)
+	return data, rssi
+}
+
+// ReceiveContext is like Receive, but also returns the packet's
+// link quality indicator (LQI), and stops waiting and sets the radio's
+// error state to ctx.Err() if the context is cancelled before a packet arrives.
+func (r *Radio) ReceiveContext(ctx context.Context, timeout time.Duration) ([]byte, int, uint8) {
 	r.Strobe(SRX)
 	defer r.Strobe(SIDLE)
+	return r.receive(ctx, timeout, 0)
+}
+
+// Wait for a packet once the radio is listening, draining the RX FIFO
+// while the packet is being received. In infinite packet length mode,
+// length is the expected packet length; otherwise it is 0.
+func (r *Radio) receive(ctx context.Context, timeout time.Duration, length int) ([]byte, int, uint8) {
 	if verbose {
 		log.Printf("waiting for interrupt in %s state", r.State())
 	}
-	r.hw.AwaitInterrupt(timeout)
+	r.awaitInterrupt(ctx, timeout)
+	if ctx.Err() != nil {
+		r.SetError(ctx.Err())
+		return nil, minRSSI, 0
+	}
+	_, threshold := r.FIFOThresholds()
+	poll := r.fifoPoll()
+	var data []byte
+	var restore func()
 	for r.Error() == nil && r.hw.ReadInterrupt() {
-		n := r.ReadNumRXBytes()
+		n := int(r.ReadNumRXBytes())
 		if verbose {
 			log.Printf("  interrupt still asserted with %d bytes in FIFO", n)
 		}
-		if n >= fifoSize {
-			break
+		if length != 0 && restore == nil && len(data)+n > length-256 {
+			restore = r.endInfinite(length)
+			defer restore()
 		}
-		time.Sleep(deassertPoll)
+		if n < threshold {
+			time.Sleep(poll)
+			continue
+		}
+		// Leave a byte in the RX FIFO until the end of the packet,
+		// since reading the last byte while receiving can corrupt it.
+		data = append(data, r.hw.ReadBurst(RXFIFO, n-1)...)
+	}
+	if r.err == ErrRXFIFOOverflow {
+		// Keep the error when the radio is returned to IDLE.
+		r.SetError(r.err)
+		return nil, minRSSI, 0
 	}
 	numBytes := int(r.ReadNumRXBytes())
-	if numBytes == 0 {
+	if numBytes == 0 && len(data) == 0 {
 		r.SetError(ErrReceiveTimeout)
-		return nil, minRSSI
+		return nil, minRSSI, 0
 	}
-	data := r.hw.ReadBurst(RXFIFO, numBytes)
+	data = append(data, r.hw.ReadBurst(RXFIFO, numBytes)...)
 	if r.hw.ReadInterrupt() {
 		r.SetError(fmt.Errorf("interrupt still asserted with %d bytes in FIFO", numBytes))
 	}
 	if r.Error() != nil {
-		return nil, minRSSI
+		return nil, minRSSI, 0
+	}
+	return r.verifyPacket(data, length)
+}
+
+// Wait for an interrupt in slices of at most cancelPoll,
+// so that cancellation of the context is noticed promptly.
+func (r *Radio) awaitInterrupt(ctx context.Context, timeout time.Duration) {
+	if ctx.Done() == nil {
+		r.hw.AwaitInterrupt(timeout)
+		return
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		wait := time.Until(deadline)
+		if wait > cancelPoll {
+			wait = cancelPoll
+		}
+		r.hw.AwaitInterrupt(wait)
+		if r.hw.Error() == nil || ctx.Err() != nil || !time.Now().Before(deadline) {
+			return
+		}
+		// Distinguish a timeout from an I/O error by reading the pin.
+		if r.hw.ReadInterrupt() || r.hw.Error() != nil {
+			return
+		}
 	}
-	return r.verifyPacket(data, numBytes)
 }
 
 // Check whether packet has correct length byte and valid CRC.
-// Return the body of the packet (or nil if invalid) and the RSSI.
-func (r *Radio) verifyPacket(data []byte, numBytes int) ([]byte, int) {
-	if numBytes < 4 {
-		r.SetError(fmt.Errorf("invalid %d-byte packet", numBytes))
-		return nil, minRSSI
+// Return the body of the packet (or nil if invalid), the RSSI, and the LQI.
+// Fixed-length packets have no length byte, nor do packets received
+// in infinite packet length mode, whose expected length is given.
+func (r *Radio) verifyPacket(data []byte, length int) ([]byte, int, uint8) {
+	numBytes := len(data)
+	fixed, n := r.fixedLength()
+	if length != 0 {
+		fixed, n = true, length
+	}
+	hdr := 1
+	if fixed {
+		hdr = 0
+	}
+	if numBytes < hdr+3 {
+		r.SetError(LengthError{Data: data, Expected: hdr + 3, RSSI: minRSSI})
+		return nil, minRSSI, 0
 	}
-	lenByte := int(data[0])
 	rssi := registerToRSSI(data[numBytes-2])
 	status := data[numBytes-1]
-	crcOK := status&(1<<7) != 0
+	lqi := status & PKT_APPEND_STATUS_1_LQI_MASK
+	crcOK := status&PKT_APPEND_STATUS_1_CRC_OK != 0
 	if !crcOK {
-		r.SetError(fmt.Errorf("invalid CRC: % X (RSSI %d)", data, rssi))
-		return nil, rssi
+		r.SetError(CRCError{Data: data, RSSI: rssi})
+		return nil, rssi, lqi
+	}
+	if !fixed {
+		n = int(data[0])
 	}
-	lqi := status &^ (1 << 7)
-	if lenByte != numBytes-3 {
-		r.SetError(fmt.Errorf("incorrect length: % X (RSSI %d)", data, rssi))
-		return nil, rssi
+	if numBytes != hdr+n+2 {
+		r.SetError(LengthError{Data: data, Expected: hdr + n + 2, RSSI: rssi})
+		return nil, rssi, lqi
 	}
-	packet := data[1 : numBytes-2]
+	packet := data[hdr : numBytes-2]
 	if verbose {
 		log.Printf("received packet with RSSI %d, LQI %02X: % X", rssi, lqi, packet)
 	}
-	return packet, rssi
+	return packet, rssi, lqi
+}
+
+// fixedLength reports whether the radio is configured for fixed-length packets,
+// and if so, their length.
+func (r *Radio) fixedLength() (bool, int) {
+	if r.hw.ReadRegister(PKTCTRL0)&PKTCTRL0_LENGTH_CONFIG_MASK != PKTCTRL0_LENGTH_CONFIG_FIXED {
+		return false, 0
+	}
+	return true, int(r.hw.ReadRegister(PKTLEN))
 }
 
 // Send transmits the given packet.
+// Packets longer than the TX FIFO are streamed into it during transmission.
 func (r *Radio) Send(data []byte) {
-	if len(data)+1 > fifoSize {
-		log.Panicf("attempting to send %d-byte packet", len(data))
+	t := r.txPacket(data)
+	if t == nil {
+		return
 	}
+	defer r.Strobe(SIDLE)
+	defer t.close()
+	t.fill(fifoSize)
+	r.Strobe(STX)
+	t.stream()
+}
+
+// Prepare the bytes to be written to the TX FIFO for the given packet,
+// or return nil if it cannot be sent.
+func (r *Radio) txPacket(data []byte) *txStream {
 	if r.Error() != nil {
-		return
+		return nil
 	}
 	if verbose {
 		log.Printf("sending %d-byte packet in %s state", len(data), r.State())
 	}
-	packet := append([]byte{byte(len(data))}, data...)
-	defer r.Strobe(SIDLE)
-	r.hw.WriteBurst(TXFIFO, packet)
-	r.Strobe(STX)
+	t := &txStream{r: r, packet: data}
+	switch r.lengthConfig() {
+	case PKTCTRL0_LENGTH_CONFIG_FIXED:
+		n := int(r.hw.ReadRegister(PKTLEN))
+		if len(data) != n {
+			r.SetError(fmt.Errorf("attempting to send %d-byte packet with fixed length %d", len(data), n))
+			return nil
+		}
+	case PKTCTRL0_LENGTH_CONFIG_VARIABLE:
+		if len(data) > maxVariableLength {
+			r.SetError(PacketSizeError{Length: len(data), Max: maxVariableLength})
+			return nil
+		}
+		t.packet = append([]byte{byte(len(data))}, data...)
+	case PKTCTRL0_LENGTH_CONFIG_INFINITE:
+		if len(data) == 0 {
+			r.SetError(fmt.Errorf("attempting to send empty packet in infinite length mode"))
+			return nil
+		}
+		t.infinite = true
+	default:
+		r.SetError(fmt.Errorf("invalid packet length configuration"))
+		return nil
+	}
+	return t
+}
+
+// Wait for the TX FIFO to drain.
+func (r *Radio) awaitTX() {
+	deadline := time.Now().Add(r.txFIFOTime())
 	for r.Error() == nil {
 		n := r.ReadNumTXBytes()
-		if n == 0 || r.Error() == ErrTXFIFOUnderflow {
+		if r.Error() != nil {
+			break
+		}
+		// The last byte leaves the FIFO before it and the CRC are sent.
+		if n == 0 && r.ReadMARCState() != MARCSTATE_TX {
+			break
+		}
+		if time.Now().After(deadline) {
+			r.SetError(ErrTransmitTimeout)
 			break
 		}
 		if verbose {
@@ -116,11 +273,26 @@ func (r *Radio) Send(data []byte) {
 		}
 		time.Sleep(time.Millisecond)
 	}
+	if r.err == ErrTXFIFOUnderflow {
+		// Keep the error when the radio is returned to IDLE.
+		r.SetError(r.err)
+	}
 	if verbose {
 		log.Printf("TX finished in %s state", r.State())
 	}
 }
 
+// Longest time to transmit the contents of the TX FIFO,
+// including the longest preamble, sync word, and CRC.
+func (r *Radio) txFIFOTime() time.Duration {
+	_, drate := r.ReadChannelParams()
+	if drate == 0 {
+		return txSlack
+	}
+	const maxBytes = 24 + 4 + fifoSize + 2
+	return time.Duration(8*maxBytes*uint64(time.Second)/uint64(drate)) + txSlack
+}
+
 // SendAndReceive transmits the given packet,
 // then listens with the given timeout for an incoming packet.
 // It returns the packet and the associated RSSI.
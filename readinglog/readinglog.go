@@ -0,0 +1,293 @@
+// Package readinglog stores received G4 packets in a directory of
+// JSON Lines files, one packet per line, and replays them later.
+//
+// New packets are appended to the file "readings.jsonl".
+// When it grows beyond the size limit, it is renamed to
+// "readings-YYYYMMDDTHHMMSS.NNNNNNNNNZ.jsonl" (using the time of rotation)
+// and a new file is started.
+package readinglog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ecc1/cc2500"
+)
+
+const (
+	currentName   = "readings.jsonl"
+	rotatedPrefix = "readings-"
+	rotatedSuffix = ".jsonl"
+	rotatedLayout = "20060102T150405.000000000Z"
+
+	// DefaultMaxSize is the default size limit of a log file, in bytes.
+	DefaultMaxSize = 4 << 20
+)
+
+// Writer appends packets to a reading log.
+type Writer struct {
+	dir     string
+	maxSize int64
+	f       *os.File
+	size    int64
+}
+
+// Create opens the reading log in the given directory for appending,
+// creating the directory if necessary. Files are rotated when they
+// exceed maxSize bytes; if maxSize is 0, DefaultMaxSize is used.
+func Create(dir string, maxSize int64) (*Writer, error) {
+	if maxSize == 0 {
+		maxSize = DefaultMaxSize
+	}
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	w := &Writer{dir: dir, maxSize: maxSize}
+	err = w.open()
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *Writer) open() error {
+	f, err := os.OpenFile(filepath.Join(w.dir, currentName), os.O_RDWR|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	size, err := truncatePartialLine(f)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	w.f = f
+	w.size = size
+	return nil
+}
+
+// Remove an incomplete final line, as left by an interrupted write,
+// so that the next packet starts on a line of its own.
+// The new size of the file is returned.
+func truncatePartialLine(f *os.File) (int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	end := info.Size()
+	buf := make([]byte, 4096)
+	for end > 0 {
+		n := int64(len(buf))
+		if n > end {
+			n = end
+		}
+		_, err = f.ReadAt(buf[:n], end-n)
+		if err != nil {
+			return 0, err
+		}
+		i := bytes.LastIndexByte(buf[:n], '\n')
+		if i >= 0 {
+			end = end - n + int64(i) + 1
+			break
+		}
+		end -= n
+	}
+	if end == info.Size() {
+		return end, nil
+	}
+	return end, f.Truncate(end)
+}
+
+// Append writes the packet to the log and flushes it to stable storage.
+func (w *Writer) Append(p *cc2500.Packet) error {
+	line, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	if w.size != 0 && w.size+int64(len(line)) > w.maxSize {
+		err = w.rotate()
+		if err != nil {
+			return err
+		}
+	}
+	n, err := w.f.Write(line)
+	w.size += int64(n)
+	if err != nil {
+		return err
+	}
+	return w.f.Sync()
+}
+
+func (w *Writer) rotate() error {
+	err := w.f.Close()
+	if err != nil {
+		return err
+	}
+	name := rotatedPrefix + time.Now().UTC().Format(rotatedLayout) + rotatedSuffix
+	err = os.Rename(filepath.Join(w.dir, currentName), filepath.Join(w.dir, name))
+	if err != nil {
+		return err
+	}
+	return w.open()
+}
+
+// Close closes the log.
+func (w *Writer) Close() error {
+	return w.f.Close()
+}
+
+// Files returns the pathnames of the log files in the given directory,
+// oldest first.
+func Files(dir string) ([]string, error) {
+	rotated, err := filepath.Glob(filepath.Join(dir, rotatedPrefix+"*"+rotatedSuffix))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(rotated)
+	current := filepath.Join(dir, currentName)
+	_, err = os.Stat(current)
+	if err == nil {
+		return append(rotated, current), nil
+	}
+	if os.IsNotExist(err) {
+		return rotated, nil
+	}
+	return nil, err
+}
+
+// ReadDir reads all the packets in the log in the given directory,
+// in the order they were appended.
+// Malformed lines in any of the files are skipped and reported
+// in a MalformedLinesError, as in Read.
+func ReadDir(dir string) ([]*cc2500.Packet, error) {
+	files, err := Files(dir)
+	if err != nil {
+		return nil, err
+	}
+	var packets []*cc2500.Packet
+	var malformed MalformedLinesError
+	for _, file := range files {
+		v, err := ReadFile(file)
+		if e, ok := err.(MalformedLinesError); ok {
+			malformed = append(malformed, e...)
+		} else if err != nil {
+			return nil, err
+		}
+		packets = append(packets, v...)
+	}
+	if len(malformed) != 0 {
+		return packets, malformed
+	}
+	return packets, nil
+}
+
+// ReadFile reads the packets in a single log file.
+// An incomplete final line, as left by an interrupted write, is ignored.
+func ReadFile(path string) ([]*cc2500.Packet, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f, path)
+}
+
+// LineError describes a line of a log file that could not be parsed.
+type LineError struct {
+	Name string
+	Line int
+	Err  error
+}
+
+func (e LineError) Error() string {
+	return fmt.Sprintf("%s:%d: %v", e.Name, e.Line, e.Err)
+}
+
+// MalformedLinesError is returned along with the packets that were read
+// when some lines could not be parsed. Those lines are skipped.
+type MalformedLinesError []LineError
+
+func (e MalformedLinesError) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	return fmt.Sprintf("%v (and %d more malformed lines)", e[0], len(e)-1)
+}
+
+// Read reads packets in JSON Lines format from r.
+// The name is used in error messages.
+// Malformed lines are skipped and reported in a MalformedLinesError,
+// which is returned along with the packets from the other lines.
+func Read(r io.Reader, name string) ([]*cc2500.Packet, error) {
+	var packets []*cc2500.Packet
+	var malformed MalformedLinesError
+	br := bufio.NewReader(r)
+	for lineNum := 1; ; lineNum++ {
+		line, err := br.ReadBytes('\n')
+		if err == io.EOF {
+			// Ignore an incomplete final line.
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		p := &cc2500.Packet{}
+		err = json.Unmarshal(line, p)
+		if err != nil {
+			malformed = append(malformed, LineError{Name: name, Line: lineNum, Err: err})
+			continue
+		}
+		packets = append(packets, p)
+	}
+	if len(malformed) != 0 {
+		return packets, malformed
+	}
+	return packets, nil
+}
+
+// Replay starts a goroutine to deliver the given packets on the returned channel.
+// If speed is positive, packets are delivered with the original intervals
+// between their timestamps divided by speed; otherwise they are delivered
+// as fast as they are received. The channel is closed after the last packet
+// or when the context is cancelled.
+func Replay(ctx context.Context, packets []*cc2500.Packet, speed float64) <-chan *cc2500.Packet {
+	readings := make(chan *cc2500.Packet, 10)
+	go func() {
+		defer close(readings)
+		start := time.Now()
+		for i, p := range packets {
+			if speed > 0 && i != 0 {
+				elapsed := p.Timestamp.Sub(packets[0].Timestamp)
+				wait := time.Until(start.Add(time.Duration(float64(elapsed) / speed)))
+				if wait > 0 {
+					timer := time.NewTimer(wait)
+					select {
+					case <-timer.C:
+					case <-ctx.Done():
+						timer.Stop()
+						return
+					}
+				}
+			}
+			select {
+			case readings <- p:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return readings
+}
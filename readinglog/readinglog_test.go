@@ -0,0 +1,176 @@
+package readinglog
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ecc1/cc2500"
+)
+
+var t0 = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func testPackets(n int) []*cc2500.Packet {
+	packets := make([]*cc2500.Packet, n)
+	for i := range packets {
+		p := &cc2500.Packet{
+			Timestamp:     t0.Add(time.Duration(i) * 5 * time.Minute),
+			Channel:       i % 4,
+			TransmitterID: "6GN7J",
+			Sequence:      uint8(i),
+			Raw:           uint32(150000 + 100*i),
+			Filtered:      uint32(150000 + 100*i),
+			Battery:       215,
+			RSSI:          -60 - i,
+		}
+		p.Data, _ = p.MarshalBinary()
+		packets[i] = p
+	}
+	return packets
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "readinglog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestWriteRead(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	w, err := Create(dir, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	packets := testPackets(10)
+	for _, p := range packets {
+		err = w.Append(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := Files(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) < 2 {
+		t.Errorf("Files() == %v, want rotated files", files)
+	}
+	if filepath.Base(files[len(files)-1]) != currentName {
+		t.Errorf("last file == %s, want %s", files[len(files)-1], currentName)
+	}
+	read, err := ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, packets) {
+		t.Errorf("ReadDir() == %v, want %v", read, packets)
+	}
+}
+
+func TestReadIncompleteLine(t *testing.T) {
+	input := `{"TransmitterID":"6GN7J","Raw":1}` + "\n" + `{"TransmitterID":"6G`
+	packets, err := Read(strings.NewReader(input), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packets) != 1 || packets[0].Raw != 1 {
+		t.Errorf("Read() == %v", packets)
+	}
+}
+
+func TestReadMalformedLines(t *testing.T) {
+	input := "bogus\n" + `{"TransmitterID":"6GN7J","Raw":1}` + "\n" + "{\n"
+	packets, err := Read(strings.NewReader(input), "test")
+	e, ok := err.(MalformedLinesError)
+	if !ok {
+		t.Fatalf("Read() error == %v, want MalformedLinesError", err)
+	}
+	if len(e) != 2 || e[0].Line != 1 || e[1].Line != 3 {
+		t.Errorf("Read() reported malformed lines %v, want 1 and 3", e)
+	}
+	if len(packets) != 1 || packets[0].Raw != 1 {
+		t.Errorf("Read() == %v", packets)
+	}
+}
+
+func TestAppendAfterPartialLine(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	packets := testPackets(2)
+	w, err := Create(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.Append(packets[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Simulate a write interrupted by a crash.
+	_, err = w.f.Write([]byte(`{"TransmitterID":"6G`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	w, err = Create(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.Append(packets[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	read, err := ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, packets) {
+		t.Errorf("ReadDir() == %v, want %v", read, packets)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	packets := testPackets(4)
+	// 15 minutes of readings at 1e6 times real time take about 1ms.
+	start := time.Now()
+	var replayed []*cc2500.Packet
+	for p := range Replay(context.Background(), packets, 1e6) {
+		replayed = append(replayed, p)
+	}
+	if !reflect.DeepEqual(replayed, packets) {
+		t.Errorf("Replay() == %v, want %v", replayed, packets)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("Replay() took %v", time.Since(start))
+	}
+}
+
+func TestReplayCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	readings := Replay(ctx, testPackets(4), 1)
+	<-readings
+	cancel()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-readings:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("Replay() channel not closed after cancellation")
+		}
+	}
+}
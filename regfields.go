@@ -0,0 +1,449 @@
+package cc2500
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Field describes a bitfield within a configuration register,
+// as listed in section 33 of the data sheet.
+type Field struct {
+	Register byte   // register address
+	Name     string // data sheet name of the field
+	Shift    uint   // position of the least significant bit
+	Width    uint   // number of bits
+	Reserved bool   // reserved for future use or test purposes
+	Volatile bool   // updated by the chip, such as calibration results
+
+	format func(byte) string
+}
+
+// Mask returns the bits of the register occupied by the field.
+func (f Field) Mask() byte {
+	return byte((1<<f.Width)-1) << f.Shift
+}
+
+// Describe returns a human-readable description of the given field value.
+func (f Field) Describe(v byte) string {
+	if f.format == nil {
+		return fmt.Sprintf("%d", v)
+	}
+	return f.format(v)
+}
+
+// FieldValue is a configuration register field and its value.
+type FieldValue struct {
+	Field
+	Value byte
+}
+
+func (v FieldValue) String() string {
+	s := fmt.Sprintf("%s.%s = %#x", RegisterName(v.Register), v.Name, v.Value)
+	if v.format != nil {
+		s += " (" + v.format(v.Value) + ")"
+	}
+	return s
+}
+
+// DecodedConfiguration is an RFConfiguration broken down into its fields,
+// in register order and from most to least significant bit.
+type DecodedConfiguration []FieldValue
+
+// Decode breaks down the configuration into its fields.
+func (config *RFConfiguration) Decode() DecodedConfiguration {
+	regs := config.Bytes()
+	d := make(DecodedConfiguration, len(Fields))
+	for i, f := range Fields {
+		d[i] = FieldValue{Field: f, Value: (regs[f.Register] & f.Mask()) >> f.Shift}
+	}
+	return d
+}
+
+// Configuration reassembles the register values from the fields.
+func (d DecodedConfiguration) Configuration() RFConfiguration {
+	regs := make([]byte, rfConfigurationSize)
+	for _, v := range d {
+		regs[v.Register] |= (v.Value << v.Shift) & v.Mask()
+	}
+	var config RFConfiguration
+	_ = config.UnmarshalBinary(regs)
+	return config
+}
+
+// Lookup returns the value of the field with the given name,
+// which may be qualified by its register name ("MCSM1.CCA_MODE")
+// to distinguish fields with the same name.
+func (d DecodedConfiguration) Lookup(name string) (FieldValue, bool) {
+	for _, v := range d {
+		if v.Name == name || RegisterName(v.Register)+"."+v.Name == name {
+			return v, true
+		}
+	}
+	return FieldValue{}, false
+}
+
+// Set changes the value of the field with the given name.
+func (d DecodedConfiguration) Set(name string, value byte) error {
+	for i, v := range d {
+		if v.Name != name && RegisterName(v.Register)+"."+v.Name != name {
+			continue
+		}
+		if value > v.Mask()>>v.Shift {
+			return fmt.Errorf("%s value %#x does not fit in %d bits", name, value, v.Width)
+		}
+		d[i].Value = value
+		return nil
+	}
+	return fmt.Errorf("unknown register field %s", name)
+}
+
+// RegisterName returns the name of the configuration register
+// with the given address.
+func RegisterName(addr byte) string {
+	if int(addr) >= len(registerName) {
+		return fmt.Sprintf("%#02x", addr)
+	}
+	return registerName[addr]
+}
+
+var registerName = []string{
+	"IOCFG2", "IOCFG1", "IOCFG0", "FIFOTHR", "SYNC1", "SYNC0", "PKTLEN", "PKTCTRL1",
+	"PKTCTRL0", "ADDR", "CHANNR", "FSCTRL1", "FSCTRL0", "FREQ2", "FREQ1", "FREQ0",
+	"MDMCFG4", "MDMCFG3", "MDMCFG2", "MDMCFG1", "MDMCFG0", "DEVIATN", "MCSM2", "MCSM1",
+	"MCSM0", "FOCCFG", "BSCFG", "AGCCTRL2", "AGCCTRL1", "AGCCTRL0", "WOREVT1", "WOREVT0",
+	"WORCTRL", "FREND1", "FREND0", "FSCAL3", "FSCAL2", "FSCAL1", "FSCAL0", "RCCTRL1",
+	"RCCTRL0", "FSTEST", "PTEST", "AGCTEST", "TEST2", "TEST1", "TEST0",
+}
+
+func enum(names ...string) func(byte) string {
+	return func(v byte) string {
+		if int(v) < len(names) && names[v] != "" {
+			return names[v]
+		}
+		return "reserved"
+	}
+}
+
+func onOff(v byte) string {
+	if v != 0 {
+		return "enabled"
+	}
+	return "disabled"
+}
+
+func unit(u string) func(byte) string {
+	return func(v byte) string {
+		return fmt.Sprintf("%d %s", v, u)
+	}
+}
+
+func field(reg byte, name string, hi, lo uint, format func(byte) string) Field {
+	return Field{Register: reg, Name: name, Shift: lo, Width: hi - lo + 1, format: format}
+}
+
+func result(reg byte, name string, hi, lo uint) Field {
+	f := field(reg, name, hi, lo, nil)
+	f.Volatile = true
+	return f
+}
+
+func reserved(reg byte, hi, lo uint) Field {
+	return Field{Register: reg, Name: "reserved", Shift: lo, Width: hi - lo + 1, Reserved: true}
+}
+
+var gdoConfig = enum(
+	"RX FIFO at or above threshold",
+	"RX FIFO at or above threshold or end of packet",
+	"TX FIFO at or above threshold",
+	"TX FIFO full",
+	"RX FIFO overflow",
+	"TX FIFO underflow",
+	"sync word sent or received",
+	"packet received with CRC OK",
+	"preamble quality reached",
+	"clear channel",
+	"PLL lock",
+	"serial clock",
+	"serial synchronous data output",
+	"serial data output",
+	"carrier sense",
+	"CRC OK",
+	"", "", "", "", "", "",
+	"RX_HARD_DATA[1]",
+	"RX_HARD_DATA[0]",
+	"", "", "",
+	"PA_PD",
+	"LNA_PD",
+	"RX_SYMBOL_TICK",
+	"", "", "", "", "", "",
+	"WOR_EVNT0",
+	"WOR_EVNT1",
+	"",
+	"CLK_32k",
+	"",
+	"CHIP_RDYn",
+	"",
+	"XOSC_STABLE",
+	"",
+	"GDO0_Z_EN_N",
+	"high impedance",
+	"hardwired to 0",
+	"CLK_XOSC/1", "CLK_XOSC/1.5", "CLK_XOSC/2", "CLK_XOSC/3",
+	"CLK_XOSC/4", "CLK_XOSC/6", "CLK_XOSC/8", "CLK_XOSC/12",
+	"CLK_XOSC/16", "CLK_XOSC/24", "CLK_XOSC/32", "CLK_XOSC/48",
+	"CLK_XOSC/64", "CLK_XOSC/96", "CLK_XOSC/128", "CLK_XOSC/192",
+)
+
+func fifoThreshold(v byte) string {
+	return fmt.Sprintf("TX FIFO %d bytes, RX FIFO %d bytes", 61-4*int(v), 4+4*int(v))
+}
+
+func carrierSenseThreshold(v byte) string {
+	t := int(v)
+	if t >= 8 {
+		t -= 16
+	}
+	switch {
+	case t == -8:
+		return "disabled"
+	case t < 0:
+		return fmt.Sprintf("%d dB below MAGN_TARGET", -t)
+	case t > 0:
+		return fmt.Sprintf("%d dB above MAGN_TARGET", t)
+	default:
+		return "at MAGN_TARGET"
+	}
+}
+
+var offModes = enum("IDLE", "FSTXON", "TX", "RX")
+
+// Fields lists every bitfield of every configuration register.
+var Fields = []Field{
+	reserved(IOCFG2, 7, 7),
+	field(IOCFG2, "GDO2_INV", 6, 6, enum("active high", "active low")),
+	field(IOCFG2, "GDO2_CFG", 5, 0, gdoConfig),
+
+	field(IOCFG1, "GDO_DS", 7, 7, enum("low drive strength", "high drive strength")),
+	field(IOCFG1, "GDO1_INV", 6, 6, enum("active high", "active low")),
+	field(IOCFG1, "GDO1_CFG", 5, 0, gdoConfig),
+
+	field(IOCFG0, "TEMP_SENSOR_ENABLE", 7, 7, onOff),
+	field(IOCFG0, "GDO0_INV", 6, 6, enum("active high", "active low")),
+	field(IOCFG0, "GDO0_CFG", 5, 0, gdoConfig),
+
+	reserved(FIFOTHR, 7, 4),
+	field(FIFOTHR, "FIFO_THR", 3, 0, fifoThreshold),
+
+	field(SYNC1, "SYNC[15:8]", 7, 0, nil),
+	field(SYNC0, "SYNC[7:0]", 7, 0, nil),
+
+	field(PKTLEN, "PACKET_LENGTH", 7, 0, unit("bytes")),
+
+	field(PKTCTRL1, "PQT", 7, 5, nil),
+	reserved(PKTCTRL1, 4, 4),
+	field(PKTCTRL1, "CRC_AUTOFLUSH", 3, 3, onOff),
+	field(PKTCTRL1, "APPEND_STATUS", 2, 2, onOff),
+	field(PKTCTRL1, "ADR_CHK", 1, 0, enum(
+		"no address check",
+		"address check, no broadcast",
+		"address check, 0x00 broadcast",
+		"address check, 0x00 and 0xFF broadcast",
+	)),
+
+	reserved(PKTCTRL0, 7, 7),
+	field(PKTCTRL0, "WHITE_DATA", 6, 6, onOff),
+	field(PKTCTRL0, "PKT_FORMAT", 5, 4, enum(
+		"normal",
+		"synchronous serial",
+		"random TX",
+		"asynchronous serial",
+	)),
+	field(PKTCTRL0, "CC2400_EN", 3, 3, onOff),
+	field(PKTCTRL0, "CRC_EN", 2, 2, onOff),
+	field(PKTCTRL0, "LENGTH_CONFIG", 1, 0, enum("fixed", "variable", "infinite")),
+
+	field(ADDR, "DEVICE_ADDR", 7, 0, nil),
+
+	field(CHANNR, "CHAN", 7, 0, nil),
+
+	reserved(FSCTRL1, 7, 5),
+	field(FSCTRL1, "FREQ_IF", 4, 0, func(v byte) string {
+		return fmt.Sprintf("%d Hz", uint64(v)*FXOSC>>10)
+	}),
+
+	field(FSCTRL0, "FREQOFF", 7, 0, func(v byte) string {
+		return fmt.Sprintf("%d Hz", registerToFrequencyOffset(v))
+	}),
+
+	field(FREQ2, "FREQ[23:16]", 7, 0, nil),
+	field(FREQ1, "FREQ[15:8]", 7, 0, nil),
+	field(FREQ0, "FREQ[7:0]", 7, 0, nil),
+
+	field(MDMCFG4, "CHANBW_E", 7, 6, nil),
+	field(MDMCFG4, "CHANBW_M", 5, 4, nil),
+	field(MDMCFG4, "DRATE_E", 3, 0, nil),
+
+	field(MDMCFG3, "DRATE_M", 7, 0, nil),
+
+	field(MDMCFG2, "DEM_DCFILT_OFF", 7, 7, enum("DC blocking filter enabled", "DC blocking filter disabled")),
+	field(MDMCFG2, "MOD_FORMAT", 6, 4, enum(modFormat...)),
+	field(MDMCFG2, "MANCHESTER_EN", 3, 3, onOff),
+	field(MDMCFG2, "SYNC_MODE", 2, 0, enum(syncMode...)),
+
+	field(MDMCFG1, "FEC_EN", 7, 7, onOff),
+	field(MDMCFG1, "NUM_PREAMBLE", 6, 4, func(v byte) string {
+		return fmt.Sprintf("%d bytes", numPreamble[v])
+	}),
+	reserved(MDMCFG1, 3, 2),
+	field(MDMCFG1, "CHANSPC_E", 1, 0, nil),
+
+	field(MDMCFG0, "CHANSPC_M", 7, 0, nil),
+
+	reserved(DEVIATN, 7, 7),
+	field(DEVIATN, "DEVIATION_E", 6, 4, nil),
+	reserved(DEVIATN, 3, 3),
+	field(DEVIATN, "DEVIATION_M", 2, 0, nil),
+
+	reserved(MCSM2, 7, 5),
+	field(MCSM2, "RX_TIME_RSSI", 4, 4, onOff),
+	field(MCSM2, "RX_TIME_QUAL", 3, 3, enum("continue RX if sync word found", "continue RX if PQI reached")),
+	field(MCSM2, "RX_TIME", 2, 0, func(v byte) string {
+		if v == MCSM2_RX_TIME_END_OF_PACKET {
+			return "until end of packet"
+		}
+		return fmt.Sprintf("timeout index %d", v)
+	}),
+
+	reserved(MCSM1, 7, 6),
+	field(MCSM1, "CCA_MODE", 5, 4, enum(
+		"always",
+		"if RSSI below threshold",
+		"unless receiving a packet",
+		"if RSSI below threshold unless receiving a packet",
+	)),
+	field(MCSM1, "RXOFF_MODE", 3, 2, offModes),
+	field(MCSM1, "TXOFF_MODE", 1, 0, offModes),
+
+	reserved(MCSM0, 7, 6),
+	field(MCSM0, "FS_AUTOCAL", 5, 4, enum(
+		"never",
+		"from IDLE to RX or TX",
+		"from RX or TX to IDLE",
+		"every 4th time from RX or TX to IDLE",
+	)),
+	field(MCSM0, "PO_TIMEOUT", 3, 2, enum("count 1", "count 16", "count 64", "count 256")),
+	field(MCSM0, "PIN_CTRL_EN", 1, 1, onOff),
+	field(MCSM0, "XOSC_FORCE_ON", 0, 0, onOff),
+
+	reserved(FOCCFG, 7, 6),
+	field(FOCCFG, "FOC_BS_CS_GATE", 5, 5, onOff),
+	field(FOCCFG, "FOC_PRE_K", 4, 3, enum("K", "2K", "3K", "4K")),
+	field(FOCCFG, "FOC_POST_K", 2, 2, enum("same as FOC_PRE_K", "K/2")),
+	field(FOCCFG, "FOC_LIMIT", 1, 0, enum("0", "BW/8", "BW/4", "BW/2")),
+
+	field(BSCFG, "BS_PRE_KI", 7, 6, enum("KI", "2KI", "3KI", "4KI")),
+	field(BSCFG, "BS_PRE_KP", 5, 4, enum("KP", "2KP", "3KP", "4KP")),
+	field(BSCFG, "BS_POST_KI", 3, 3, enum("same as BS_PRE_KI", "KI/2")),
+	field(BSCFG, "BS_POST_KP", 2, 2, enum("same as BS_PRE_KP", "KP")),
+	field(BSCFG, "BS_LIMIT", 1, 0, enum("0", "3.125%", "6.25%", "12.5%")),
+
+	field(AGCCTRL2, "MAX_DVGA_GAIN", 7, 6, enum(
+		"all gain settings",
+		"all but the highest gain setting",
+		"all but the 2 highest gain settings",
+		"all but the 3 highest gain settings",
+	)),
+	field(AGCCTRL2, "MAX_LNA_GAIN", 5, 3, enum(
+		"maximum",
+		"2.6 dB below maximum",
+		"6.1 dB below maximum",
+		"7.4 dB below maximum",
+		"9.2 dB below maximum",
+		"11.5 dB below maximum",
+		"14.6 dB below maximum",
+		"17.1 dB below maximum",
+	)),
+	field(AGCCTRL2, "MAGN_TARGET", 2, 0, enum("24 dB", "27 dB", "30 dB", "33 dB", "36 dB", "38 dB", "40 dB", "42 dB")),
+
+	reserved(AGCCTRL1, 7, 7),
+	field(AGCCTRL1, "AGC_LNA_PRIORITY", 6, 6, enum("reduce LNA 2 gain first", "reduce LNA gain first")),
+	field(AGCCTRL1, "CARRIER_SENSE_REL_THR", 5, 4, enum("disabled", "6 dB increase", "10 dB increase", "14 dB increase")),
+	field(AGCCTRL1, "CARRIER_SENSE_ABS_THR", 3, 0, carrierSenseThreshold),
+
+	field(AGCCTRL0, "HYST_LEVEL", 7, 6, enum("none", "low", "medium", "large")),
+	field(AGCCTRL0, "WAIT_TIME", 5, 4, enum("8 samples", "16 samples", "24 samples", "32 samples")),
+	field(AGCCTRL0, "AGC_FREEZE", 3, 2, enum(
+		"normal",
+		"freeze when sync word found",
+		"freeze analog gain",
+		"freeze analog and digital gain",
+	)),
+	field(AGCCTRL0, "FILTER_LENGTH", 1, 0, enum("8 samples", "16 samples", "32 samples", "64 samples")),
+
+	field(WOREVT1, "EVENT0[15:8]", 7, 0, nil),
+	field(WOREVT0, "EVENT0[7:0]", 7, 0, nil),
+
+	field(WORCTRL, "RC_PD", 7, 7, enum("RC oscillator powered up", "RC oscillator powered down")),
+	field(WORCTRL, "EVENT1", 6, 4, enum(
+		"4 clock periods", "6 clock periods", "8 clock periods", "12 clock periods",
+		"16 clock periods", "24 clock periods", "32 clock periods", "48 clock periods",
+	)),
+	field(WORCTRL, "RC_CAL", 3, 3, onOff),
+	reserved(WORCTRL, 2, 2),
+	field(WORCTRL, "WOR_RES", 1, 0, enum("1 period", "2^5 periods", "2^10 periods", "2^15 periods")),
+
+	field(FREND1, "LNA_CURRENT", 7, 6, nil),
+	field(FREND1, "LNA2MIX_CURRENT", 5, 4, nil),
+	field(FREND1, "LODIV_BUF_CURRENT_RX", 3, 2, nil),
+	field(FREND1, "MIX_CURRENT", 1, 0, nil),
+
+	reserved(FREND0, 7, 6),
+	field(FREND0, "LODIV_BUF_CURRENT_TX", 5, 4, nil),
+	reserved(FREND0, 3, 3),
+	field(FREND0, "PA_POWER", 2, 0, func(v byte) string {
+		return fmt.Sprintf("PATABLE 0..%d", v)
+	}),
+
+	field(FSCAL3, "FSCAL3[7:6]", 7, 6, nil),
+	field(FSCAL3, "CHP_CURR_CAL_EN", 5, 4, nil),
+	result(FSCAL3, "FSCAL3[3:0]", 3, 0),
+
+	reserved(FSCAL2, 7, 6),
+	field(FSCAL2, "VCO_CORE_H_EN", 5, 5, onOff),
+	result(FSCAL2, "FSCAL2", 4, 0),
+
+	reserved(FSCAL1, 7, 6),
+	result(FSCAL1, "FSCAL1", 5, 0),
+
+	reserved(FSCAL0, 7, 7),
+	field(FSCAL0, "FSCAL0", 6, 0, nil),
+
+	reserved(RCCTRL1, 7, 7),
+	field(RCCTRL1, "RCCTRL1", 6, 0, nil),
+
+	reserved(RCCTRL0, 7, 7),
+	field(RCCTRL0, "RCCTRL0", 6, 0, nil),
+
+	field(FSTEST, "FSTEST", 7, 0, nil),
+	field(PTEST, "PTEST", 7, 0, nil),
+	field(AGCTEST, "AGCTEST", 7, 0, nil),
+	field(TEST2, "TEST2", 7, 0, nil),
+	field(TEST1, "TEST1", 7, 0, nil),
+
+	field(TEST0, "TEST0[7:2]", 7, 2, nil),
+	field(TEST0, "VCO_SEL_CAL_EN", 1, 1, onOff),
+	field(TEST0, "TEST0[0]", 0, 0, nil),
+}
+
+// String formats the decoded configuration with one field per line.
+func (d DecodedConfiguration) String() string {
+	var b strings.Builder
+	for _, v := range d {
+		if v.Reserved {
+			continue
+		}
+		b.WriteString(v.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
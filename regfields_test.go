@@ -0,0 +1,85 @@
+package cc2500
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestFieldsCoverRegisters(t *testing.T) {
+	var covered [TEST0 + 1]byte
+	for _, f := range Fields {
+		if covered[f.Register]&f.Mask() != 0 {
+			t.Errorf("%s.%s overlaps another field", RegisterName(f.Register), f.Name)
+		}
+		covered[f.Register] |= f.Mask()
+	}
+	for addr, bits := range covered {
+		if bits != 0xFF {
+			t.Errorf("fields of %s cover bits %08b", RegisterName(byte(addr)), bits)
+		}
+	}
+}
+
+func TestDecodeRoundTrip(t *testing.T) {
+	configs := []RFConfiguration{ResetRFConfiguration}
+	g4, _ := G4Profile.Configuration()
+	configs = append(configs, g4)
+	for i := 0; i < 10; i++ {
+		regs := make([]byte, rfConfigurationSize)
+		rand.Read(regs)
+		var config RFConfiguration
+		_ = config.UnmarshalBinary(regs)
+		configs = append(configs, config)
+	}
+	for _, config := range configs {
+		d := config.Decode()
+		c := d.Configuration()
+		if c != config {
+			t.Errorf("Decode().Configuration() == % X, want % X", c.Bytes(), config.Bytes())
+		}
+	}
+}
+
+func TestDecodedFields(t *testing.T) {
+	g4, _ := G4Profile.Configuration()
+	d := g4.Decode()
+	cases := []struct {
+		name string
+		text string
+	}{
+		{"GDO0_CFG", "IOCFG0.GDO0_CFG = 0x6 (sync word sent or received)"},
+		{"MOD_FORMAT", "MDMCFG2.MOD_FORMAT = 0x7 (MSK)"},
+		{"LENGTH_CONFIG", "PKTCTRL0.LENGTH_CONFIG = 0x1 (variable)"},
+		{"MCSM1.RXOFF_MODE", "MCSM1.RXOFF_MODE = 0x0 (IDLE)"},
+		{"FREQ_IF", "FSCTRL1.FREQ_IF = 0x9 (228515 Hz)"},
+		{"CARRIER_SENSE_ABS_THR", "AGCCTRL1.CARRIER_SENSE_ABS_THR = 0x0 (at MAGN_TARGET)"},
+	}
+	for _, c := range cases {
+		v, ok := d.Lookup(c.name)
+		if !ok {
+			t.Errorf("Lookup(%s) failed", c.name)
+			continue
+		}
+		if v.String() != c.text {
+			t.Errorf("%s == %q, want %q", c.name, v.String(), c.text)
+		}
+	}
+	err := d.Set("SYNC_MODE", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := d.Configuration()
+	if c.MDMCFG2 != MDMCFG2_MOD_FORMAT_MSK|MDMCFG2_SYNC_MODE_16_16 {
+		t.Errorf("MDMCFG2 after Set() == %02X", c.MDMCFG2)
+	}
+	if d.Set("SYNC_MODE", 8) == nil {
+		t.Errorf("Set() with out-of-range value succeeded")
+	}
+	if d.Set("BOGUS", 0) == nil {
+		t.Errorf("Set() of unknown field succeeded")
+	}
+	if strings.Contains(d.String(), "reserved") {
+		t.Errorf("String() includes reserved fields")
+	}
+}
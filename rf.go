@@ -2,7 +2,8 @@ package cc2500
 
 import (
 	"errors"
-	"unsafe"
+	"fmt"
+	"math"
 )
 
 var (
@@ -13,9 +14,61 @@ var (
 	ErrTXFIFOUnderflow = errors.New("TXFIFO underflow")
 )
 
-// Bytes returns the RFConfiguration as a byte slice.
+// rfConfigurationSize is the number of configuration registers.
+const rfConfigurationSize = TEST0 - IOCFG2 + 1
+
+// ConfigurationLengthError indicates an attempt to unmarshal
+// an RFConfiguration from the wrong number of bytes.
+type ConfigurationLengthError struct {
+	Length int
+}
+
+func (e ConfigurationLengthError) Error() string {
+	return fmt.Sprintf("RFConfiguration requires %d bytes, not %d", rfConfigurationSize, e.Length)
+}
+
+// registers returns pointers to the fields of the RFConfiguration,
+// in register address order.
+func (config *RFConfiguration) registers() []*byte {
+	return []*byte{
+		&config.IOCFG2, &config.IOCFG1, &config.IOCFG0, &config.FIFOTHR,
+		&config.SYNC1, &config.SYNC0, &config.PKTLEN, &config.PKTCTRL1,
+		&config.PKTCTRL0, &config.ADDR, &config.CHANNR, &config.FSCTRL1,
+		&config.FSCTRL0, &config.FREQ2, &config.FREQ1, &config.FREQ0,
+		&config.MDMCFG4, &config.MDMCFG3, &config.MDMCFG2, &config.MDMCFG1,
+		&config.MDMCFG0, &config.DEVIATN, &config.MCSM2, &config.MCSM1,
+		&config.MCSM0, &config.FOCCFG, &config.BSCFG, &config.AGCCTRL2,
+		&config.AGCCTRL1, &config.AGCCTRL0, &config.WOREVT1, &config.WOREVT0,
+		&config.WORCTRL, &config.FREND1, &config.FREND0, &config.FSCAL3,
+		&config.FSCAL2, &config.FSCAL1, &config.FSCAL0, &config.RCCTRL1,
+		&config.RCCTRL0, &config.FSTEST, &config.PTEST, &config.AGCTEST,
+		&config.TEST2, &config.TEST1, &config.TEST0,
+	}
+}
+
+// Bytes returns a copy of the register values in address order.
 func (config *RFConfiguration) Bytes() []byte {
-	return (*[TEST0 - IOCFG2 + 1]byte)(unsafe.Pointer(config))[:]
+	data := make([]byte, rfConfigurationSize)
+	for i, p := range config.registers() {
+		data[i] = *p
+	}
+	return data
+}
+
+// MarshalBinary returns the register values in address order.
+func (config *RFConfiguration) MarshalBinary() ([]byte, error) {
+	return config.Bytes(), nil
+}
+
+// UnmarshalBinary sets the registers from values in address order.
+func (config *RFConfiguration) UnmarshalBinary(data []byte) error {
+	if len(data) != rfConfigurationSize {
+		return ConfigurationLengthError{Length: len(data)}
+	}
+	for i, p := range config.registers() {
+		*p = data[i]
+	}
+	return nil
 }
 
 // ReadConfiguration reads the current RFConfiguration from the radio.
@@ -23,121 +76,37 @@ func (r *Radio) ReadConfiguration() *RFConfiguration {
 	if r.Error() != nil {
 		return nil
 	}
-	regs := r.hw.ReadBurst(IOCFG2, TEST0-IOCFG2+1)
-	return (*RFConfiguration)(unsafe.Pointer(&regs[0]))
+	regs := r.hw.ReadBurst(IOCFG2, rfConfigurationSize)
+	if r.Error() != nil {
+		return nil
+	}
+	config := &RFConfiguration{}
+	err := config.UnmarshalBinary(regs)
+	if err != nil {
+		r.SetError(err)
+		return nil
+	}
+	return config
 }
 
 // WriteConfiguration writes the given RFConfiguration to the radio.
 func (r *Radio) WriteConfiguration(config *RFConfiguration) {
 	r.hw.WriteBurst(IOCFG2, config.Bytes())
+	if !r.verify || r.Error() != nil {
+		return
+	}
+	diff := r.VerifyConfiguration(config)
+	if diff != nil {
+		r.SetError(VerifyError{Diff: diff})
+	}
 }
 
 // InitRF initializes the radio to communicate with
 // a Dexcom G4 continuous glucose monitor at the given frequency.
 func (r *Radio) InitRF(frequency uint32) {
-	rf := ResetRFConfiguration
-	fb := frequencyToRegisters(frequency)
-
-	// Asserts when sync word has been sent/received,
-	// and de-asserts at the end of the packet.
-	rf.IOCFG0 = 0x06
-
-	rf.SYNC1 = 0xD3
-	rf.SYNC0 = 0x91
-
-	rf.PKTCTRL1 = PKTCTRL1_APPEND_STATUS | PKTCTRL1_ADR_CHK_NONE
-	rf.PKTCTRL0 = PKTCTRL0_CRC_EN | PKTCTRL0_LENGTH_CONFIG_VARIABLE
-
-	// Intermediate frequency
-	// 0x09 * 26 MHz / 2^10 == 228515 Hz
-	rf.FSCTRL1 = 0x09
-
-	rf.FREQ2 = fb[0]
-	rf.FREQ1 = fb[1]
-	rf.FREQ0 = fb[2]
-
-	// See table 20 in data sheet.
-	// CHANBW_E = 1, CHANBW_M = 1, DRATE_E = 10
-	// Channel BW = 26 MHz / (8 * (4 + CHANBW_M) * 2^CHANBW_E) == 325 kHz
-	// See data sheet section 13 and Dexcom's FCC filing at
-	// https://apps.fcc.gov/eas/GetApplicationAttachment.html?id=1373548
-	rf.MDMCFG4 = 1<<MDMCFG4_CHANBW_E_SHIFT |
-		1<<MDMCFG4_CHANBW_M_SHIFT |
-		10<<MDMCFG4_DRATE_E_SHIFT
-
-	// DRATE_M = 248 (0xF8)
-	// Data rate = (256 + DRATE_M) * 2^DRATE_E * 26 MHz / 2^28 == 49987 Baud
-	rf.MDMCFG3 = 0xF8
-
-	rf.MDMCFG2 = MDMCFG2_DEM_DCFILT_ON |
-		MDMCFG2_MOD_FORMAT_MSK |
-		MDMCFG2_SYNC_MODE_30_32
-
-	// CHANSPC_E = 3
-	rf.MDMCFG1 = MDMCFG1_FEC_DIS |
-		MDMCFG1_NUM_PREAMBLE_2 |
-		3<<MDMCFG1_CHANSPC_E_SHIFT
-
-	// CHANSPC_M = 59 (0x3B)
-	// Channel spacing = (256 + CHANSPC_M) * 2^CHANSPC_E * 26 MHz / 2^18 == 249938 Hz
-	rf.MDMCFG0 = 0x3B
-
-	rf.DEVIATN = 0x40
-
-	rf.MCSM2 = MCSM2_RX_TIME_END_OF_PACKET
-
-	rf.MCSM1 = MCSM1_CCA_MODE_ALWAYS |
-		MCSM1_RXOFF_MODE_IDLE |
-		MCSM1_TXOFF_MODE_IDLE
-
-	rf.MCSM0 = MCSM0_FS_AUTOCAL_FROM_IDLE
-
-	rf.FOCCFG = FOCCFG_FOC_PRE_K_2K |
-		FOCCFG_FOC_POST_K_PRE_K |
-		FOCCFG_FOC_LIMIT_BW_OVER_4
-
-	rf.BSCFG = BSCFG_BS_PRE_KI_2KI |
-		BSCFG_BS_PRE_KP_3KP |
-		BSCFG_BS_POST_KI_PRE_KI_OVER_2 |
-		BSCFG_BS_POST_KP_PRE_KP |
-		BSCFG_BS_LIMIT_0
-
-	rf.AGCCTRL2 = AGCCTRL2_MAX_DVGA_GAIN_BUT_1 |
-		AGCCTRL2_MAX_LNA_GAIN_0 |
-		AGCCTRL2_MAGN_TARGET_36dB
-
-	rf.AGCCTRL1 = AGCCTRL1_AGC_LNA_PRIORITY_0 |
-		AGCCTRL1_CARRIER_SENSE_REL_THR_DISABLE |
-		AGCCTRL1_CARRIER_SENSE_ABS_THR_0DB
-
-	rf.AGCCTRL0 = AGCCTRL0_HYST_LEVEL_MEDIUM |
-		AGCCTRL0_WAIT_TIME_32 |
-		AGCCTRL0_AGC_FREEZE_NORMAL |
-		AGCCTRL0_FILTER_LENGTH_32
-
-	rf.FREND1 = 2<<FREND1_LNA_CURRENT_SHIFT |
-		3<<FREND1_LNA2MIX_CURRENT_SHIFT |
-		1<<FREND1_LODIV_BUF_CURRENT_RX_SHIFT |
-		2<<FREND1_MIX_CURRENT_SHIFT
-
-	// Use PA_TABLE 1 for transmitting '1' in ASK
-	// (PA_TABLE 0 is always used for '0')
-	rf.FREND0 = 1<<FREND0_LODIV_BUF_CURRENT_TX_SHIFT |
-		0<<FREND0_PA_POWER_SHIFT
-
-	rf.FSCAL3 = 2<<6 | 2<<4 | 0x09
-	rf.FSCAL2 = 0x0A
-	rf.FSCAL1 = 0x00
-	rf.FSCAL0 = 0x20
-
-	rf.TEST2 = TEST2_RX_LOW_DATA_RATE_MAGIC
-	rf.TEST1 = TEST1_RX_LOW_DATA_RATE_MAGIC
-	rf.TEST0 = 2<<2 | 1<<1 | 1<<0
-
-	r.WriteConfiguration(&rf)
-
-	// Power amplifier output settings (see section 24 of the data sheet)
-	r.hw.WriteRegister(PATABLE, 0xBB)
+	p := G4Profile
+	p.Frequency = frequency
+	r.WriteProfile(p)
 }
 
 // Frequency returns the radio's current frequency, in Hertz.
@@ -177,13 +146,11 @@ func (r *Radio) ReadIF() uint32 {
 // ReadChannelParams returns the radio's channel bandwidth and data rate.
 func (r *Radio) ReadChannelParams() (uint32, uint32) {
 	m4 := r.hw.ReadRegister(MDMCFG4)
-	chanbwExp := (m4 >> MDMCFG4_CHANBW_E_SHIFT) & 0x3
-	chanbwMant := (m4 >> MDMCFG4_CHANBW_M_SHIFT) & 0x3
-	drateExp := (m4 >> MDMCFG4_DRATE_E_SHIFT) & 0xF
+	chanbwExp := (m4 & MDMCFG4_CHANBW_E_MASK) >> MDMCFG4_CHANBW_E_SHIFT
+	chanbwMant := (m4 & MDMCFG4_CHANBW_M_MASK) >> MDMCFG4_CHANBW_M_SHIFT
+	drateExp := (m4 & MDMCFG4_DRATE_E_MASK) >> MDMCFG4_DRATE_E_SHIFT
 	drateMant := r.hw.ReadRegister(MDMCFG3)
-	chanbw := uint32(FXOSC / ((4 + uint64(chanbwMant)) << (chanbwExp + 3)))
-	drate := uint32(((256 + uint64(drateMant)) << drateExp * FXOSC) >> 28)
-	return chanbw, drate
+	return channelBandwidth(int(chanbwExp), int(chanbwMant)), dataRate(int(drateExp), int(drateMant))
 }
 
 // ReadModemConfig returns the radio's modem configuration:
@@ -194,12 +161,55 @@ func (r *Radio) ReadModemConfig() (bool, uint8, uint32) {
 	minPreamble := numPreamble[(m1&MDMCFG1_NUM_PREAMBLE_MASK)>>4]
 	chanspcExp := m1 & MDMCFG1_CHANSPC_E_MASK
 	chanspcMant := r.hw.ReadRegister(MDMCFG0)
-	chanspc := uint32(((256 + uint64(chanspcMant)) << chanspcExp * FXOSC) >> 18)
-	return fec, minPreamble, chanspc
+	return fec, minPreamble, channelSpacing(int(chanspcExp), int(chanspcMant))
+}
+
+// ReadDeviation returns the radio's frequency deviation, in Hertz.
+// For MSK, DEVIATN has a different meaning; see the data sheet.
+func (r *Radio) ReadDeviation() uint32 {
+	d := r.hw.ReadRegister(DEVIATN)
+	e := (d & DEVIATN_DEVIATION_E_MASK) >> DEVIATN_DEVIATION_E_SHIFT
+	m := (d & DEVIATN_DEVIATION_M_MASK) >> DEVIATN_DEVIATION_M_SHIFT
+	return deviation(int(e), int(m))
+}
+
+// SetDataRate sets the radio's data rate to the closest available
+// setting to the given value, in Baud.
+func (r *Radio) SetDataRate(rate uint32) {
+	e, m, _ := DataRateRegisters(rate)
+	m4 := r.hw.ReadRegister(MDMCFG4) &^ MDMCFG4_DRATE_E_MASK
+	r.hw.WriteRegister(MDMCFG4, m4|e<<MDMCFG4_DRATE_E_SHIFT)
+	r.hw.WriteRegister(MDMCFG3, m<<MDMCFG3_DRATE_M_SHIFT)
+}
+
+// SetChannelBandwidth sets the radio's channel filter bandwidth
+// to the closest available setting to the given value, in Hertz.
+func (r *Radio) SetChannelBandwidth(bw uint32) {
+	e, m, _ := BandwidthRegisters(bw)
+	m4 := r.hw.ReadRegister(MDMCFG4) &^ (MDMCFG4_CHANBW_E_MASK | MDMCFG4_CHANBW_M_MASK)
+	r.hw.WriteRegister(MDMCFG4, m4|e<<MDMCFG4_CHANBW_E_SHIFT|m<<MDMCFG4_CHANBW_M_SHIFT)
+}
+
+// SetDeviation sets the radio's frequency deviation
+// to the closest available setting to the given value, in Hertz.
+func (r *Radio) SetDeviation(dev uint32) {
+	e, m, _ := DeviationRegisters(dev)
+	d := r.hw.ReadRegister(DEVIATN) &^ (DEVIATN_DEVIATION_E_MASK | DEVIATN_DEVIATION_M_MASK)
+	r.hw.WriteRegister(DEVIATN, d|e<<DEVIATN_DEVIATION_E_SHIFT|m<<DEVIATN_DEVIATION_M_SHIFT)
+}
+
+// SetChannelSpacing sets the radio's channel spacing
+// to the closest available setting to the given value, in Hertz.
+func (r *Radio) SetChannelSpacing(spacing uint32) {
+	e, m, _ := ChannelSpacingRegisters(spacing)
+	m1 := r.hw.ReadRegister(MDMCFG1) &^ MDMCFG1_CHANSPC_E_MASK
+	r.hw.WriteRegister(MDMCFG1, m1|e<<MDMCFG1_CHANSPC_E_SHIFT)
+	r.hw.WriteRegister(MDMCFG0, m<<MDMCFG0_CHANSPC_M_SHIFT)
 }
 
+const rssiOffset = 72 // see data sheet section 17.3
+
 func registerToRSSI(rssi byte) int {
-	const rssiOffset = 72 // see data sheet section 17.3
 	d := int(rssi)
 	if d >= 128 {
 		d -= 256
@@ -207,6 +217,17 @@ func registerToRSSI(rssi byte) int {
 	return d/2 - rssiOffset
 }
 
+func rssiToRegister(rssi int) byte {
+	d := 2 * (rssi + rssiOffset)
+	if d < math.MinInt8 {
+		d = math.MinInt8
+	}
+	if d > math.MaxInt8 {
+		d = math.MaxInt8
+	}
+	return byte(d)
+}
+
 // ReadRSSI returns the radio's RSSI, in dBm.
 func (r *Radio) ReadRSSI() int {
 	return registerToRSSI(r.hw.ReadRegister(RSSI))
@@ -217,6 +238,11 @@ func (r *Radio) ReadPATable() []byte {
 	return r.hw.ReadBurst(PATABLE, 8)
 }
 
+// WritePATable writes the given values to PATABLE.
+func (r *Radio) WritePATable(table []byte) {
+	r.hw.WriteBurst(PATABLE, table)
+}
+
 // ReadNumRXBytes reads the RXBYTES register
 // and detects RXFIFO overflow.
 func (r *Radio) ReadNumRXBytes() byte {
@@ -224,6 +250,7 @@ func (r *Radio) ReadNumRXBytes() byte {
 	if n&RXFIFO_OVERFLOW != 0 {
 		r.Strobe(SFRX)
 		r.err = ErrRXFIFOOverflow
+		r.metrics.rxOverflow()
 	}
 	return n & NUM_RXBYTES_MASK
 }
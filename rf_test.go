@@ -36,3 +36,64 @@ func TestFrequency(t *testing.T) {
 		}
 	}
 }
+
+func TestRegisterCalculators(t *testing.T) {
+	cases := []struct {
+		name   string
+		calc   func(uint32) (byte, byte, uint32)
+		want   uint32
+		e, m   byte
+		actual uint32
+	}{
+		{"DataRate", DataRateRegisters, 50000, 10, 248, 49987},
+		{"DataRate", DataRateRegisters, 2400, 6, 131, 2398},
+		{"DataRate", DataRateRegisters, 500000, 14, 59, 499877},
+		{"Bandwidth", BandwidthRegisters, 325000, 1, 1, 325000},
+		{"Bandwidth", BandwidthRegisters, 60000, 3, 3, 58035},
+		{"Bandwidth", BandwidthRegisters, 1000000, 0, 0, 812500},
+		{"Deviation", DeviationRegisters, 25000, 4, 0, 25390},
+		{"Deviation", DeviationRegisters, 47000, 4, 7, 47607},
+		{"Deviation", DeviationRegisters, 1500, 0, 0, 1586},
+		{"ChannelSpacing", ChannelSpacingRegisters, 250000, 3, 59, 249938},
+		{"ChannelSpacing", ChannelSpacingRegisters, 200000, 2, 248, 199951},
+		{"ChannelSpacing", ChannelSpacingRegisters, 1000000, 3, 255, 405456},
+	}
+	for _, c := range cases {
+		e, m, actual := c.calc(c.want)
+		if e != c.e || m != c.m || actual != c.actual {
+			t.Errorf("%sRegisters(%d) == %d, %d, %d; want %d, %d, %d", c.name, c.want, e, m, actual, c.e, c.m, c.actual)
+		}
+	}
+}
+
+func TestSetModemParams(t *testing.T) {
+	r, _ := openSimulator(t)
+	m2 := r.hw.ReadRegister(MDMCFG2)
+	r.SetDataRate(250000)
+	r.SetChannelBandwidth(541667)
+	r.SetDeviation(38000)
+	r.SetChannelSpacing(333000)
+	if r.Error() != nil {
+		t.Fatal(r.Error())
+	}
+	chanbw, drate := r.ReadChannelParams()
+	_, _, dr := DataRateRegisters(250000)
+	if drate != dr {
+		t.Errorf("data rate == %d, want %d", drate, dr)
+	}
+	if chanbw != 541666 {
+		t.Errorf("channel bandwidth == %d, want %d", chanbw, 541666)
+	}
+	_, _, dev := DeviationRegisters(38000)
+	if r.ReadDeviation() != dev {
+		t.Errorf("deviation == %d, want %d", r.ReadDeviation(), dev)
+	}
+	fec, preamble, chanspc := r.ReadModemConfig()
+	_, _, spc := ChannelSpacingRegisters(333000)
+	if chanspc != spc || fec || preamble != 2 {
+		t.Errorf("ReadModemConfig() == %v, %d, %d; want false, 2, %d", fec, preamble, chanspc, spc)
+	}
+	if r.hw.ReadRegister(MDMCFG2) != m2 {
+		t.Errorf("MDMCFG2 changed")
+	}
+}
@@ -0,0 +1,306 @@
+package cc2500
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Settings are register values to be loaded into the radio,
+// such as those designed with TI's SmartRF Studio.
+type Settings struct {
+	Config  RFConfiguration
+	PATable []byte // nil if not specified
+}
+
+// SettingsError indicates an invalid register setting.
+type SettingsError struct {
+	Line     int // 0 if not read from a file
+	Register string
+	Reason   string
+}
+
+func (e SettingsError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("register %s: %s", e.Register, e.Reason)
+	}
+	return fmt.Sprintf("line %d: register %s: %s", e.Line, e.Register, e.Reason)
+}
+
+// Line formats recognized by ParseSettings.
+var settingPatterns = []*regexp.Regexp{
+	// SmartRF Studio C header export:
+	//   #define SMARTRF_SETTING_IOCFG0 0x06
+	regexp.MustCompile(`^#define\s+SMARTRF_SETTING_(\w+)\s+(\w+)`),
+	// SmartRF Studio function call templates:
+	//   halRfWriteReg(IOCFG0,0x06);
+	regexp.MustCompile(`^\w+\(\s*(\w+)\s*,\s*(\w+)\s*\)`),
+	// SmartRF Studio array initializer templates:
+	//   {CC2500_IOCFG0, 0x06},
+	regexp.MustCompile(`^\{\s*(\w+)\s*,\s*(\w+)\s*\}`),
+	// SmartRF Studio register table templates (name, address, value):
+	//   IOCFG0 0x0002 0x06 GDO0 Output Pin Configuration
+	regexp.MustCompile(`^(\w+)\s+(0x[[:xdigit:]]{4})\s+(0x[[:xdigit:]]{1,2})\b`),
+	// Simple text format, with an optional comma-separated list for PATABLE:
+	//   IOCFG0=0x06
+	//   PATABLE=0xBB,0x00
+	regexp.MustCompile(`^(\w+)\s*(?:=|\s)\s*(\w+(?:\s*,\s*\w+)*)`),
+}
+
+// ParseSettings reads register settings in any of the formats
+// exported by SmartRF Studio, or in a simple text format
+// with one "REGISTER=0xVV" setting per line.
+// Blank lines and comments beginning with "#" or "//" are ignored.
+// Registers that are not mentioned keep their reset values.
+func ParseSettings(r io.Reader) (Settings, error) {
+	s := Settings{Config: ResetRFConfiguration}
+	regs := s.Config.Bytes()
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(text, "//"); i >= 0 {
+			text = strings.TrimSpace(text[:i])
+		}
+		if text == "" || strings.HasPrefix(text, "#") && !strings.HasPrefix(text, "#define") {
+			continue
+		}
+		name, addr, values := matchSetting(text)
+		if name == "" {
+			if strings.HasPrefix(text, "#define") {
+				// Other SmartRF Studio definitions, such as SMARTRF_RADIO_CC2500.
+				continue
+			}
+			return s, SettingsError{line, text, "unrecognized setting"}
+		}
+		name = strings.ToUpper(name)
+		if seen[name] {
+			return s, SettingsError{line, name, "set more than once"}
+		}
+		seen[name] = true
+		if i, ok := paTableIndex(name); ok {
+			if err := s.setPATable(i, values); err != nil {
+				return s, SettingsError{line, name, err.Error()}
+			}
+			continue
+		}
+		reg, err := lookupRegister(name)
+		if err != nil {
+			return s, SettingsError{line, name, err.Error()}
+		}
+		if addr != "" {
+			a, _ := strconv.ParseUint(addr, 0, 16)
+			if a != uint64(reg) {
+				return s, SettingsError{line, name, fmt.Sprintf("has address %#02x, not %s", reg, addr)}
+			}
+		}
+		v, err := parseByte(values[0])
+		if err != nil {
+			return s, SettingsError{line, name, err.Error()}
+		}
+		if err := checkTestRegister(reg, v); err != nil {
+			return s, SettingsError{line, name, err.Error()}
+		}
+		regs[reg] = v
+	}
+	if err := scanner.Err(); err != nil {
+		return s, err
+	}
+	if err := s.Config.UnmarshalBinary(regs); err != nil {
+		return s, err
+	}
+	return s, nil
+}
+
+// LoadSettings reads register settings from the named file.
+func LoadSettings(name string) (Settings, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return Settings{}, err
+	}
+	defer f.Close()
+	return ParseSettings(f)
+}
+
+func matchSetting(text string) (name string, addr string, values []string) {
+	for _, p := range settingPatterns {
+		m := p.FindStringSubmatch(text)
+		switch len(m) {
+		case 3:
+			return m[1], "", strings.Split(m[2], ",")
+		case 4:
+			return m[1], m[2], []string{m[3]}
+		}
+	}
+	return "", "", nil
+}
+
+// paTableIndex recognizes PATABLE, PATABLEn, PA_TABLE, and PA_TABLEn,
+// returning the index of the first entry being set.
+func paTableIndex(name string) (int, bool) {
+	for _, prefix := range []string{"PATABLE", "PA_TABLE"} {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		suffix := name[len(prefix):]
+		if suffix == "" {
+			return 0, true
+		}
+		if len(suffix) == 1 && '0' <= suffix[0] && suffix[0] <= '7' {
+			return int(suffix[0] - '0'), true
+		}
+	}
+	return 0, false
+}
+
+func (s *Settings) setPATable(index int, values []string) error {
+	if index+len(values) > 8 {
+		return fmt.Errorf("has only 8 entries")
+	}
+	for i, str := range values {
+		v, err := parseByte(strings.TrimSpace(str))
+		if err != nil {
+			return err
+		}
+		for len(s.PATable) <= index+i {
+			s.PATable = append(s.PATable, 0)
+		}
+		s.PATable[index+i] = v
+	}
+	return nil
+}
+
+// lookupRegister returns the address of the named configuration register,
+// allowing for a prefix such as "CC2500_" as used in SmartRF Studio templates.
+func lookupRegister(name string) (byte, error) {
+	for {
+		for i, n := range registerName {
+			if n == name {
+				return byte(i), nil
+			}
+		}
+		if statusRegister[name] {
+			return 0, fmt.Errorf("is a read-only status register")
+		}
+		i := strings.Index(name, "_")
+		if i < 0 {
+			return 0, fmt.Errorf("is not a configuration register")
+		}
+		name = name[i+1:]
+	}
+}
+
+var statusRegister = map[string]bool{
+	"PARTNUM": true, "VERSION": true, "FREQEST": true, "LQI": true,
+	"RSSI": true, "MARCSTATE": true, "WORTIME1": true, "WORTIME0": true,
+	"PKTSTATUS": true, "VCO_VC_DAC": true, "TXBYTES": true, "RXBYTES": true,
+	"RCCTRL1_STATUS": true, "RCCTRL0_STATUS": true,
+}
+
+func parseByte(s string) (byte, error) {
+	v, err := strconv.ParseUint(s, 0, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	return byte(v), nil
+}
+
+// checkTestRegister verifies that a test register has one of
+// the values specified in section 33 of the data sheet.
+func checkTestRegister(reg byte, v byte) error {
+	var ok bool
+	switch reg {
+	case FSTEST:
+		ok = v == 0x59
+	case PTEST:
+		// 0xBF enables the temperature sensor in IDLE state.
+		ok = v == 0x7F || v == 0xBF
+	case AGCTEST:
+		ok = v == 0x3F
+	case TEST2:
+		ok = v == TEST2_NORMAL_MAGIC || v == TEST2_RX_LOW_DATA_RATE_MAGIC
+	case TEST1:
+		ok = v == TEST1_TX_MAGIC || v == TEST1_RX_LOW_DATA_RATE_MAGIC
+	case TEST0:
+		ok = v&TEST0_7_2_MASK == 2<<2 && v&TEST0_0_MASK == 1
+	default:
+		return nil
+	}
+	if !ok {
+		return fmt.Errorf("test register value %#02x is not allowed", v)
+	}
+	return nil
+}
+
+// Validate checks that the test registers have permitted values.
+func (s Settings) Validate() error {
+	for reg, v := range s.Config.Bytes() {
+		if err := checkTestRegister(byte(reg), v); err != nil {
+			return SettingsError{0, RegisterName(byte(reg)), err.Error()}
+		}
+	}
+	if len(s.PATable) > 8 {
+		return SettingsError{0, "PATABLE", "has only 8 entries"}
+	}
+	return nil
+}
+
+// Write writes the settings in the simple text format.
+func (s Settings) Write(w io.Writer) error {
+	for reg, v := range s.Config.Bytes() {
+		if _, err := fmt.Fprintf(w, "%s=0x%02X\n", RegisterName(byte(reg)), v); err != nil {
+			return err
+		}
+	}
+	if s.PATable == nil {
+		return nil
+	}
+	table := make([]string, len(s.PATable))
+	for i, v := range s.PATable {
+		table[i] = fmt.Sprintf("0x%02X", v)
+	}
+	_, err := fmt.Fprintf(w, "PATABLE=%s\n", strings.Join(table, ","))
+	return err
+}
+
+// WriteSmartRF writes the settings in the SmartRF Studio C header format.
+func (s Settings) WriteSmartRF(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "#define SMARTRF_RADIO_CC2500\n"); err != nil {
+		return err
+	}
+	for reg, v := range s.Config.Bytes() {
+		if _, err := fmt.Fprintf(w, "#define SMARTRF_SETTING_%-8s 0x%02X\n", RegisterName(byte(reg)), v); err != nil {
+			return err
+		}
+	}
+	for i, v := range s.PATable {
+		if _, err := fmt.Fprintf(w, "#define SMARTRF_SETTING_PATABLE%d 0x%02X\n", i, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadSettings returns the current configuration and PATABLE contents.
+func (r *Radio) ReadSettings() Settings {
+	config := r.ReadConfiguration()
+	if config == nil {
+		return Settings{}
+	}
+	return Settings{Config: *config, PATable: r.ReadPATable()}
+}
+
+// WriteSettings loads the configuration registers
+// and, if specified, the PATABLE.
+func (r *Radio) WriteSettings(s Settings) {
+	r.WriteConfiguration(&s.Config)
+	if s.PATable != nil {
+		r.WritePATable(s.PATable)
+	}
+}
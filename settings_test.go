@@ -0,0 +1,110 @@
+package cc2500
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseSettings(t *testing.T) {
+	cases := []struct {
+		name string
+		text string
+	}{
+		{"simple", "# G4 settings\nIOCFG0=0x01\nPKTLEN = 0x1F // comment\nPATABLE=0xBB,0x00\n"},
+		{"smartrf header", "#define SMARTRF_RADIO_CC2500\n#define SMARTRF_SETTING_IOCFG0 0x01\n#define SMARTRF_SETTING_PKTLEN   0x1F\n#define SMARTRF_SETTING_PATABLE0 0xBB\n#define SMARTRF_SETTING_PATABLE1 0x00\n"},
+		{"function calls", "halRfWriteReg(IOCFG0,0x01);  //GDO0 Output Pin Configuration\nhalRfWriteReg(PKTLEN,0x1F);\nPA_TABLE=0xBB,0x00\n"},
+		{"array", "{CC2500_IOCFG0, 0x01},\n{CC2500_PKTLEN, 0x1F},\nPATABLE 0xBB, 0x00\n"},
+		{"register table", "IOCFG0 0x0002 0x01 GDO0 Output Pin Configuration\nPKTLEN 0x0006 0x1F Packet Length\nPATABLE 0xBB,0x00\n"},
+	}
+	want := ResetRFConfiguration
+	want.IOCFG0 = 0x01
+	want.PKTLEN = 0x1F
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s, err := ParseSettings(strings.NewReader(c.text))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.Config != want {
+				t.Errorf("ParseSettings() == % X, want % X", s.Config.Bytes(), want.Bytes())
+			}
+			if !bytes.Equal(s.PATable, []byte{0xBB, 0x00}) {
+				t.Errorf("ParseSettings() PATABLE == % X, want BB 00", s.PATable)
+			}
+		})
+	}
+}
+
+func TestParseSettingsErrors(t *testing.T) {
+	cases := []struct {
+		text string
+		line int
+	}{
+		{"IOCFG0=0x01\nFREQEST=0x00\n", 2},
+		{"#define SMARTRF_SETTING_VERSION 0x03\n", 1},
+		{"TEST2=0x55\n", 1},
+		{"TEST0=0x0A\n", 1},
+		{"FOO=0x01\n", 1},
+		{"IOCFG0=0x100\n", 1},
+		{"IOCFG0=1\nIOCFG0=2\n", 2},
+		{"IOCFG0 0x0003 0x01\n", 1},
+		{"PATABLE5=0x01,0x02,0x03,0x04\n", 1},
+		{"???\n", 1},
+	}
+	for _, c := range cases {
+		_, err := ParseSettings(strings.NewReader(c.text))
+		e, ok := err.(SettingsError)
+		if !ok {
+			t.Errorf("ParseSettings(%q) error == %v, want SettingsError", c.text, err)
+			continue
+		}
+		if e.Line != c.line {
+			t.Errorf("ParseSettings(%q) error on line %d, want %d", c.text, e.Line, c.line)
+		}
+	}
+}
+
+func TestSettingsRoundTrip(t *testing.T) {
+	config, err := G4Profile.Configuration()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := Settings{Config: config, PATable: G4Profile.PATable()}
+	if err := s.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, write := range []func(Settings, *bytes.Buffer) error{
+		func(s Settings, b *bytes.Buffer) error { return s.Write(b) },
+		func(s Settings, b *bytes.Buffer) error { return s.WriteSmartRF(b) },
+	} {
+		var buf bytes.Buffer
+		if err := write(s, &buf); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ParseSettings(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Config != s.Config || !bytes.Equal(got.PATable, s.PATable) {
+			t.Errorf("round trip == %+v, want %+v", got, s)
+		}
+	}
+}
+
+func TestWriteSettings(t *testing.T) {
+	r, _ := openSimulator(t)
+	s := Settings{Config: ResetRFConfiguration, PATable: []byte{0xFE, 0x00}}
+	s.Config.CHANNR = 0x12
+	r.WriteSettings(s)
+	got := r.ReadSettings()
+	if r.Error() != nil {
+		t.Fatal(r.Error())
+	}
+	if got.Config != s.Config {
+		t.Errorf("ReadSettings() == % X, want % X", got.Config.Bytes(), s.Config.Bytes())
+	}
+	if !bytes.HasPrefix(got.PATable, s.PATable) {
+		t.Errorf("ReadSettings() PATABLE == % X, want prefix % X", got.PATable, s.PATable)
+	}
+}
@@ -0,0 +1,797 @@
+package cc2500
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// SimPacket describes a packet to be received by a Simulator.
+type SimPacket struct {
+	Data    []byte // packet body, without length byte or CRC
+	RSSI    int    // in dBm
+	LQI     byte   // link quality estimate (0..127)
+	BadCRC  bool   // whether the hardware CRC check fails
+	FreqEst byte   // FREQEST value after reception
+
+	// Burst delivers the whole packet to the RX FIFO with its first byte,
+	// as if the FIFO had not been serviced while it was received.
+	Burst bool
+}
+
+// Time for the crystal oscillator to stabilize after waking up.
+const xoscStartup = 150 * time.Microsecond
+
+// Simulator is a software model of a CC2500 module.
+// It implements the Hardware interface, so a Radio opened on it
+// with OpenHardware can be exercised without SPI hardware.
+//
+// The model includes the configuration and status registers, PATABLE,
+// the RX and TX FIFOs, the IDLE/RX/TX state machine, Wake-on-Radio,
+// SLEEP and crystal-off states (with loss of the unretained registers),
+// address filtering (the address is the first byte of SimPacket.Data),
+// and the GDO0 interrupt line when IOCFG0 is 0x06
+// (asserted from sync word until end of packet).
+// Bytes enter the RX FIFO and leave the TX FIFO one at a time
+// at the configured data rate, so packets longer than the FIFOs
+// overflow or underflow them unless they are serviced in time.
+// Infinite packet length mode is supported for transmission;
+// a received packet always ends after its Data.
+type Simulator struct {
+	mu  sync.Mutex
+	err error
+
+	regs    [TEST0 + 1]byte
+	paTable [8]byte
+	state   byte
+
+	rxFIFO      []byte
+	txFIFO      []byte
+	rxOverflow  bool
+	txUnderflow bool
+
+	// Packets on the air, waiting for the radio to listen.
+	pending []SimPacket
+	// Packet whose sync word has been detected,
+	// the bytes to be delivered to the RX FIFO, and how many have been.
+	incoming *SimPacket
+	rxBytes  []byte
+	rxCount  int
+	rxStart  time.Time
+	rxEnd    time.Time
+
+	// Bytes taken from the TX FIFO for the packet being transmitted,
+	// starting at txStart; txEnd is set once the last one has been taken.
+	txData  []byte
+	txStart time.Time
+	txEnd   time.Time
+	sent    [][]byte
+
+	rssi    byte
+	lqi     byte
+	freqEst byte
+
+	// Wake-on-Radio sequence: next Event 0 and end of RX timeout.
+	wor        bool
+	worWake    time.Time
+	worTimeout time.Time
+
+	// SLEEP and crystal-off states, and when the crystal
+	// oscillator will be stable after waking up.
+	powerDown bool
+	xoff      bool
+	readyAt   time.Time
+
+	// Whether the RSSI is above the carrier sense threshold.
+	channelBusy bool
+
+	// Registers that ignore writes, as with faulty SPI wiring.
+	stuck map[byte]byte
+
+	// Signaled when a packet is injected or the radio enters RX.
+	wakeup chan struct{}
+}
+
+// NewSimulator returns a simulated CC2500 in its reset state.
+func NewSimulator() *Simulator {
+	s := &Simulator{wakeup: make(chan struct{}, 1)}
+	s.reset()
+	return s
+}
+
+// Inject queues a packet to be received the next time the radio is in RX.
+// Packets injected while the radio is not listening remain queued.
+func (s *Simulator) Inject(p SimPacket) {
+	s.mu.Lock()
+	s.pending = append(s.pending, p)
+	s.mu.Unlock()
+	s.signal()
+}
+
+// SetChannelBusy sets whether the simulated RSSI is above the
+// carrier sense threshold (if one is enabled in AGCCTRL1).
+func (s *Simulator) SetChannelBusy(busy bool) {
+	s.mu.Lock()
+	s.channelBusy = busy
+	s.mu.Unlock()
+}
+
+// StickRegister makes the given configuration register
+// hold the given value regardless of what is written to it.
+func (s *Simulator) StickRegister(addr byte, value byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stuck == nil {
+		s.stuck = make(map[byte]byte)
+	}
+	s.stuck[addr] = value
+	s.regs[addr] = value
+}
+
+// Transmitted returns the bodies of the packets transmitted
+// since the previous call.
+func (s *Simulator) Transmitted() [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sent := s.sent
+	s.sent = nil
+	return sent
+}
+
+func (s *Simulator) signal() {
+	select {
+	case s.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Simulator) reset() {
+	copy(s.regs[:], ResetRFConfiguration.Bytes())
+	for a, v := range s.stuck {
+		s.regs[a] = v
+	}
+	s.paTable = [8]byte{0xC6}
+	s.state = STATE_IDLE
+	s.wor = false
+	s.powerDown = false
+	s.xoff = false
+	s.rxFIFO = nil
+	s.txFIFO = nil
+	s.rxOverflow = false
+	s.txUnderflow = false
+	s.incoming = nil
+	s.rssi = 0x80
+	s.lqi = 0
+	s.freqEst = 0
+}
+
+// Device returns a name for the simulated device.
+func (s *Simulator) Device() string {
+	return "simulator"
+}
+
+// Error returns the error state of the simulated device.
+func (s *Simulator) Error() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// SetError sets the error state of the simulated device.
+func (s *Simulator) SetError(err error) {
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+}
+
+// Close closes the simulated device.
+func (s *Simulator) Close() {
+	s.mu.Lock()
+	s.err = nil
+	s.mu.Unlock()
+}
+
+// ReadRegister reads the given address on the simulated device.
+func (s *Simulator) ReadRegister(addr byte) byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return 0
+	}
+	s.wake(time.Now())
+	s.update(time.Now())
+	switch {
+	case addr <= TEST0:
+		return s.regs[addr]
+	case addr == PATABLE:
+		return s.paTable[0]
+	case addr == RXFIFO:
+		return s.readRXFIFO(1)[0]
+	case PARTNUM <= addr && addr <= RCCTRL0_STATUS:
+		return s.statusRegister(addr)
+	}
+	return 0
+}
+
+// ReadBurst reads a burst of n bytes from the given address on the simulated device.
+func (s *Simulator) ReadBurst(addr byte, n int) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil
+	}
+	s.wake(time.Now())
+	s.update(time.Now())
+	data := make([]byte, n)
+	switch {
+	case addr <= TEST0:
+		copy(data, s.regs[addr:])
+	case addr == PATABLE:
+		for i := range data {
+			data[i] = s.paTable[i%len(s.paTable)]
+		}
+	case addr == RXFIFO:
+		data = s.readRXFIFO(n)
+	}
+	return data
+}
+
+// WriteRegister writes the given value to the given address on the simulated device.
+func (s *Simulator) WriteRegister(addr byte, value byte) {
+	s.WriteBurst(addr, []byte{value})
+}
+
+// WriteBurst writes data in burst mode to the given address on the simulated device.
+func (s *Simulator) WriteBurst(addr byte, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = nil
+	s.wake(time.Now())
+	s.update(time.Now())
+	switch {
+	case addr <= TEST0:
+		copy(s.regs[addr:], data)
+		for a, v := range s.stuck {
+			s.regs[a] = v
+		}
+	case addr == PATABLE:
+		copy(s.paTable[:], data)
+	case addr == TXFIFO:
+		n := fifoSize - len(s.txFIFO)
+		if len(data) < n {
+			n = len(data)
+		}
+		s.txFIFO = append(s.txFIFO, data[:n]...)
+	}
+}
+
+// Strobe issues the given command strobe to the simulated device
+// and returns the chip status byte.
+func (s *Simulator) Strobe(cmd byte) (byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.wake(now)
+	s.update(now)
+	if now.Before(s.readyAt) {
+		// Commands are ignored until the crystal oscillator is stable.
+		return s.status() | CHIP_RDY, nil
+	}
+	switch cmd {
+	case SRES:
+		s.reset()
+	case SCAL:
+		if s.state == STATE_IDLE {
+			s.calibrate()
+		}
+	case SRX:
+		if s.state == STATE_IDLE || s.state == STATE_FSTXON {
+			s.autoCalibrate()
+			s.state = STATE_RX
+			s.update(now)
+			s.signal()
+		}
+	case STX:
+		if s.state == STATE_RX && !s.clearChannel() {
+			break
+		}
+		if s.state == STATE_IDLE || s.state == STATE_FSTXON || s.state == STATE_RX {
+			s.autoCalibrate()
+			s.incoming = nil
+			s.startTX(now)
+		}
+	case SFSTXON:
+		if s.state == STATE_IDLE {
+			s.state = STATE_FSTXON
+		}
+	case SWOR:
+		if s.state == STATE_IDLE {
+			// The chip sleeps between Event 0s.
+			s.loseUnretained()
+			s.wor = true
+			s.worWake = now.Add(s.worInterval())
+		}
+	case SWORRST:
+		if s.wor {
+			s.worWake = now.Add(s.worInterval())
+		}
+	case SPWD:
+		if s.state == STATE_IDLE {
+			s.powerDown = true
+		}
+	case SXOFF:
+		if s.state == STATE_IDLE {
+			s.xoff = true
+		}
+	case SIDLE:
+		s.wor = false
+		s.incoming = nil
+		if s.state != STATE_RXFIFO_OVERFLOW && s.state != STATE_TXFIFO_UNDERFLOW {
+			s.state = STATE_IDLE
+		}
+	case SFRX:
+		if s.state == STATE_IDLE || s.state == STATE_RXFIFO_OVERFLOW {
+			s.rxFIFO = nil
+			s.rxOverflow = false
+			s.state = STATE_IDLE
+		}
+	case SFTX:
+		if s.state == STATE_IDLE || s.state == STATE_TXFIFO_UNDERFLOW {
+			s.txFIFO = nil
+			s.txUnderflow = false
+			s.state = STATE_IDLE
+		}
+	}
+	return s.status(), nil
+}
+
+// Chip status byte.
+func (s *Simulator) status() byte {
+	free := fifoSize - len(s.txFIFO)
+	if free > 15 {
+		free = 15
+	}
+	return s.state<<STATE_SHIFT | byte(free)
+}
+
+// Any SPI access wakes the chip from SLEEP or crystal-off state.
+// The test registers and all but the first PATABLE entry
+// are not retained in SLEEP state.
+func (s *Simulator) wake(now time.Time) {
+	if !s.powerDown && !s.xoff {
+		return
+	}
+	if s.powerDown {
+		s.loseUnretained()
+	}
+	s.powerDown = false
+	s.xoff = false
+	s.readyAt = now.Add(xoscStartup)
+}
+
+func (s *Simulator) loseUnretained() {
+	reset := ResetRFConfiguration.Bytes()
+	copy(s.regs[FSTEST:], reset[FSTEST:])
+	for i := 1; i < len(s.paTable); i++ {
+		s.paTable[i] = 0
+	}
+}
+
+// Simulate frequency synthesizer calibration
+// by storing results that depend on the channel.
+func (s *Simulator) calibrate() {
+	ch := s.regs[CHANNR]
+	s.regs[FSCAL3] = s.regs[FSCAL3]&^0x0F | 0x0A
+	s.regs[FSCAL2] = s.regs[FSCAL2]&^0x1F | 0x11
+	s.regs[FSCAL1] = 0x20 + ch%0x20
+}
+
+func (s *Simulator) autoCalibrate() {
+	if s.state == STATE_IDLE && s.regs[MCSM0]&MCSM0_FS_AUTOCAL_MASK == MCSM0_FS_AUTOCAL_FROM_IDLE {
+		s.calibrate()
+	}
+}
+
+// AwaitInterrupt waits with the given timeout for GDO0 to be asserted.
+func (s *Simulator) AwaitInterrupt(timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for {
+		s.mu.Lock()
+		now := time.Now()
+		s.update(now)
+		if s.gdo0() {
+			s.err = nil
+			s.mu.Unlock()
+			return
+		}
+		remaining := deadline.Sub(now)
+		if remaining <= 0 {
+			s.err = fmt.Errorf("simulated GDO0 wait timeout after %v", timeout)
+			s.mu.Unlock()
+			return
+		}
+		if s.state == STATE_TX && s.txNext().Sub(now) < remaining {
+			remaining = s.txNext().Sub(now)
+		}
+		if s.wor && s.state == STATE_IDLE && s.worWake.Sub(now) < remaining {
+			remaining = s.worWake.Sub(now)
+		}
+		s.mu.Unlock()
+		select {
+		case <-s.wakeup:
+		case <-time.After(remaining):
+		}
+	}
+}
+
+// ReadInterrupt returns the state of GDO0.
+func (s *Simulator) ReadInterrupt() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = nil
+	s.update(time.Now())
+	return s.gdo0()
+}
+
+func (s *Simulator) gdo0() bool {
+	cfg := s.regs[IOCFG0]
+	level := false
+	if cfg&GDO0_CFG_MASK == 0x06 {
+		level = s.incoming != nil || s.state == STATE_TX
+	}
+	if cfg&GDO0_INV != 0 {
+		level = !level
+	}
+	return level
+}
+
+// Advance the state machine to the given time.
+func (s *Simulator) update(now time.Time) {
+	if s.state == STATE_TX {
+		s.updateTX(now)
+	}
+	if s.wor {
+		s.updateWOR(now)
+	}
+	if s.state != STATE_RX {
+		return
+	}
+	if s.incoming != nil {
+		s.updateRX(now)
+	}
+	for s.state == STATE_RX && s.incoming == nil && len(s.pending) != 0 {
+		p := s.pending[0]
+		s.pending = s.pending[1:]
+		if s.addressOK(&p) {
+			s.startRX(&p, now)
+		}
+	}
+}
+
+func (s *Simulator) startRX(p *SimPacket, now time.Time) {
+	s.incoming = p
+	s.rxBytes = nil
+	if s.variableLength() {
+		s.rxBytes = append(s.rxBytes, byte(len(p.Data)))
+	}
+	s.rxBytes = append(s.rxBytes, p.Data...)
+	s.rxCount = 0
+	s.rxStart = now.Add(s.airTime(0) - s.crcTime())
+	s.rxEnd = now.Add(s.airTime(len(s.rxBytes)))
+}
+
+// Deliver the bytes received by the given time to the RX FIFO.
+func (s *Simulator) updateRX(now time.Time) {
+	bt := s.byteTime()
+	arrival := func(i int) time.Time {
+		if s.incoming.Burst {
+			i = 0
+		}
+		return s.rxStart.Add(time.Duration(i+1) * bt)
+	}
+	for s.rxCount < len(s.rxBytes) && !now.Before(arrival(s.rxCount)) {
+		if len(s.rxFIFO) == fifoSize {
+			s.incoming = nil
+			s.overflowRX()
+			return
+		}
+		s.rxFIFO = append(s.rxFIFO, s.rxBytes[s.rxCount])
+		s.rxCount++
+	}
+	if !now.Before(s.rxEnd) {
+		p := s.incoming
+		s.incoming = nil
+		s.finishRX(p, now)
+	}
+}
+
+func (s *Simulator) overflowRX() {
+	s.rxOverflow = true
+	s.state = STATE_RXFIFO_OVERFLOW
+}
+
+// Wake up at each Event 0 and listen until the RX timeout,
+// unless a sync word has been detected.
+func (s *Simulator) updateWOR(now time.Time) {
+	rxTime := s.regs[MCSM2] & MCSM2_RX_TIME_MASK
+	noTimeout := rxTime == MCSM2_RX_TIME_END_OF_PACKET
+	if s.state == STATE_RX && s.incoming == nil && !noTimeout && !now.Before(s.worTimeout) {
+		s.state = STATE_IDLE
+	}
+	interval := s.worInterval()
+	if s.state != STATE_IDLE || now.Before(s.worWake) || interval <= 0 {
+		return
+	}
+	// Skip any Event 0 that passed while the simulator was not updated.
+	s.worWake = s.worWake.Add(now.Sub(s.worWake) / interval * interval)
+	event0 := uint16(s.regs[WOREVT1])<<8 | uint16(s.regs[WOREVT0])
+	res := s.regs[WORCTRL] & WORCTRL_WOR_RES_MASK
+	if !noTimeout {
+		s.worTimeout = s.worWake.Add(worRXTimeout(event0, res, rxTime))
+	}
+	s.worWake = s.worWake.Add(interval)
+	if noTimeout || now.Before(s.worTimeout) {
+		s.autoCalibrate()
+		s.state = STATE_RX
+	}
+}
+
+func (s *Simulator) worInterval() time.Duration {
+	event0 := uint16(s.regs[WOREVT1])<<8 | uint16(s.regs[WOREVT0])
+	return worInterval(event0, s.regs[WORCTRL]&WORCTRL_WOR_RES_MASK)
+}
+
+func (s *Simulator) finishRX(p *SimPacket, now time.Time) {
+	// A received packet ends the Wake-on-Radio sequence.
+	s.wor = false
+	s.rssi = rssiToRegister(p.RSSI)
+	s.lqi = p.LQI & LQI_LQI_EST_MASK
+	crcOK := !p.BadCRC || s.regs[PKTCTRL0]&PKTCTRL0_CRC_EN == 0
+	if crcOK {
+		s.lqi |= LQI_CRC_OK
+	}
+	s.freqEst = p.FreqEst
+	if !crcOK && s.regs[PKTCTRL1]&PKTCTRL1_CRC_AUTOFLUSH != 0 {
+		s.rxFIFO = nil
+		s.rxOff(now)
+		return
+	}
+	if s.regs[PKTCTRL1]&PKTCTRL1_APPEND_STATUS != 0 {
+		if len(s.rxFIFO)+2 > fifoSize {
+			s.rxFIFO = append(s.rxFIFO, s.rssi)[:fifoSize]
+			s.overflowRX()
+			return
+		}
+		s.rxFIFO = append(s.rxFIFO, s.rssi, s.lqi)
+	}
+	s.rxOff(now)
+}
+
+// Enter the state selected by MCSM1.RXOFF_MODE.
+func (s *Simulator) rxOff(now time.Time) {
+	switch s.regs[MCSM1] & (3 << 2) {
+	case MCSM1_RXOFF_MODE_IDLE:
+		s.state = STATE_IDLE
+	case MCSM1_RXOFF_MODE_FSTXON:
+		s.state = STATE_FSTXON
+	case MCSM1_RXOFF_MODE_TX:
+		s.startTX(now)
+	case MCSM1_RXOFF_MODE_RX:
+		s.state = STATE_RX
+	}
+}
+
+func (s *Simulator) startTX(now time.Time) {
+	if len(s.txFIFO) == 0 {
+		s.underflowTX()
+		return
+	}
+	s.state = STATE_TX
+	s.txData = nil
+	s.txStart = now.Add(s.airTime(0) - s.crcTime())
+	s.txEnd = time.Time{}
+}
+
+// Take the bytes due by the given time from the TX FIFO.
+func (s *Simulator) updateTX(now time.Time) {
+	for s.txEnd.IsZero() && !now.Before(s.txNext()) {
+		if len(s.txFIFO) == 0 {
+			s.underflowTX()
+			return
+		}
+		s.txData = append(s.txData, s.txFIFO[0])
+		s.txFIFO = s.txFIFO[1:]
+		if s.txComplete() {
+			s.txEnd = s.txNext().Add(s.crcTime())
+		}
+	}
+	if !s.txEnd.IsZero() && !now.Before(s.txEnd) {
+		s.finishTX(now)
+	}
+}
+
+// Time of the next TX event: taking a byte from the FIFO, or the end of the packet.
+func (s *Simulator) txNext() time.Time {
+	if !s.txEnd.IsZero() {
+		return s.txEnd
+	}
+	return s.txStart.Add(time.Duration(len(s.txData)) * s.byteTime())
+}
+
+// Whether the packet is complete according to the current length configuration.
+// In fixed length mode, the packet byte counter is compared with PKTLEN modulo 256,
+// so a packet started in infinite mode ends correctly after switching.
+func (s *Simulator) txComplete() bool {
+	n := len(s.txData)
+	switch s.regs[PKTCTRL0] & PKTCTRL0_LENGTH_CONFIG_MASK {
+	case PKTCTRL0_LENGTH_CONFIG_FIXED:
+		return n%256 == int(s.regs[PKTLEN])
+	case PKTCTRL0_LENGTH_CONFIG_VARIABLE:
+		return n == int(s.txData[0])+1
+	}
+	return false
+}
+
+func (s *Simulator) underflowTX() {
+	s.txUnderflow = true
+	s.state = STATE_TXFIFO_UNDERFLOW
+}
+
+func (s *Simulator) finishTX(now time.Time) {
+	body := s.txData
+	if s.variableLength() {
+		body = body[1:]
+	}
+	s.sent = append(s.sent, body)
+	s.txData = nil
+	s.txEnd = time.Time{}
+	// Enter the state selected by MCSM1.TXOFF_MODE.
+	switch s.regs[MCSM1] & 3 {
+	case MCSM1_TXOFF_MODE_IDLE:
+		s.state = STATE_IDLE
+	case MCSM1_TXOFF_MODE_FSTXON:
+		s.state = STATE_FSTXON
+	case MCSM1_TXOFF_MODE_TX:
+		s.startTX(now)
+	case MCSM1_TXOFF_MODE_RX:
+		s.state = STATE_RX
+		s.signal()
+	}
+}
+
+func (s *Simulator) carrierSense() bool {
+	a1 := s.regs[AGCCTRL1]
+	enabled := a1&AGCCTRL1_CARRIER_SENSE_ABS_THR_MASK != AGCCTRL1_CARRIER_SENSE_ABS_THR_DISABLE ||
+		a1&AGCCTRL1_CARRIER_SENSE_REL_THR_MASK != AGCCTRL1_CARRIER_SENSE_REL_THR_DISABLE
+	return enabled && s.state == STATE_RX && s.channelBusy
+}
+
+// Clear channel indication, according to MCSM1.CCA_MODE.
+func (s *Simulator) clearChannel() bool {
+	receiving := s.incoming != nil
+	switch s.regs[MCSM1] & MCSM1_CCA_MODE_MASK {
+	case MCSM1_CCA_MODE_RSSI_BELOW:
+		return !s.carrierSense()
+	case MCSM1_CCA_MODE_UNLESS_RECEIVING:
+		return !receiving
+	case MCSM1_CCA_MODE_RSSI_BELOW_UNLESS_RECEIVING:
+		return !s.carrierSense() && !receiving
+	}
+	return true
+}
+
+// Whether the packet passes the check selected by PKTCTRL1.ADR_CHK.
+func (s *Simulator) addressOK(p *SimPacket) bool {
+	mode := s.regs[PKTCTRL1] & PKTCTRL1_ADR_CHK_MASK
+	if mode == PKTCTRL1_ADR_CHK_NONE {
+		return true
+	}
+	if len(p.Data) == 0 {
+		return false
+	}
+	switch p.Data[0] {
+	case s.regs[ADDR]:
+		return true
+	case Broadcast00:
+		return mode != PKTCTRL1_ADR_CHK_NO_BROADCAST
+	case BroadcastFF:
+		return mode == PKTCTRL1_ADR_CHK_00_FF_BROADCAST
+	}
+	return false
+}
+
+func (s *Simulator) variableLength() bool {
+	return s.regs[PKTCTRL0]&3 == PKTCTRL0_LENGTH_CONFIG_VARIABLE
+}
+
+// Time on the air for a packet with n bytes of length and payload.
+func (s *Simulator) airTime(n int) time.Duration {
+	preamble := int(numPreamble[(s.regs[MDMCFG1]&MDMCFG1_NUM_PREAMBLE_MASK)>>4])
+	sync := 2
+	if s.regs[MDMCFG2]&3 == MDMCFG2_SYNC_MODE_30_32 {
+		sync = 4
+	}
+	return time.Duration(preamble+sync+n)*s.byteTime() + s.crcTime()
+}
+
+func (s *Simulator) crcTime() time.Duration {
+	if s.regs[PKTCTRL0]&PKTCTRL0_CRC_EN == 0 {
+		return 0
+	}
+	return 2 * s.byteTime()
+}
+
+// Time on the air for one byte at the configured data rate.
+func (s *Simulator) byteTime() time.Duration {
+	drateExp := s.regs[MDMCFG4] & 0xF
+	drate := ((256 + uint64(s.regs[MDMCFG3])) << drateExp * FXOSC) >> 28
+	return time.Duration(8 * uint64(time.Second) / drate)
+}
+
+func (s *Simulator) readRXFIFO(n int) []byte {
+	data := make([]byte, n)
+	k := copy(data, s.rxFIFO)
+	s.rxFIFO = s.rxFIFO[k:]
+	return data
+}
+
+func (s *Simulator) statusRegister(addr byte) byte {
+	switch addr {
+	case PARTNUM:
+		return byte(hwVersion >> 8)
+	case VERSION:
+		return byte(hwVersion & 0xFF)
+	case FREQEST:
+		return s.freqEst
+	case LQI:
+		return s.lqi
+	case RSSI:
+		return s.rssi
+	case MARCSTATE:
+		return s.marcState()
+	case PKTSTATUS:
+		var v byte
+		if s.lqi&LQI_CRC_OK != 0 {
+			v |= PKTSTATUS_CRC_OK
+		}
+		if s.gdo0() {
+			v |= PKTSTATUS_GDO0
+		}
+		if s.carrierSense() {
+			v |= PKTSTATUS_CS
+		}
+		if s.state == STATE_RX && s.clearChannel() {
+			v |= PKTSTATUS_CCA
+		}
+		return v
+	case TXBYTES:
+		v := byte(len(s.txFIFO))
+		if s.txUnderflow {
+			v |= TXFIFO_UNDERFLOW
+		}
+		return v
+	case RXBYTES:
+		v := byte(len(s.rxFIFO))
+		if s.rxOverflow {
+			v |= RXFIFO_OVERFLOW
+		}
+		return v
+	}
+	return 0
+}
+
+func (s *Simulator) marcState() byte {
+	switch s.state {
+	case STATE_RX:
+		return MARCSTATE_RX
+	case STATE_TX:
+		return MARCSTATE_TX
+	case STATE_FSTXON:
+		return MARCSTATE_FSTXON
+	case STATE_RXFIFO_OVERFLOW:
+		return MARCSTATE_RX_OVERFLOW
+	case STATE_TXFIFO_UNDERFLOW:
+		return MARCSTATE_TX_UNDERFLOW
+	}
+	return MARCSTATE_IDLE
+}
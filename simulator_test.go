@@ -0,0 +1,88 @@
+package cc2500
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func openSimulator(t *testing.T) (*Radio, *Simulator) {
+	s := NewSimulator()
+	r := OpenHardware(s)
+	if r.Error() != nil {
+		t.Fatal(r.Error())
+	}
+	r.Init(BaseFrequency)
+	return r, s
+}
+
+func TestSimulatorReceive(t *testing.T) {
+	r, s := openSimulator(t)
+	s.Inject(SimPacket{Data: p1, RSSI: -70, LQI: 0x20})
+	data, rssi, lqi := r.ReceiveContext(context.Background(), time.Second)
+	if r.Error() != nil {
+		t.Fatal(r.Error())
+	}
+	if !bytes.Equal(data, p1) {
+		t.Errorf("Receive() == % X, want % X", data, p1)
+	}
+	if rssi != -70 {
+		t.Errorf("Receive() RSSI == %d, want %d", rssi, -70)
+	}
+	if lqi != 0x20 {
+		t.Errorf("Receive() LQI == %02X, want %02X", lqi, 0x20)
+	}
+	if r.State() != "IDLE" {
+		t.Errorf("state after Receive() == %s, want IDLE", r.State())
+	}
+}
+
+func TestSimulatorReceiveTimeout(t *testing.T) {
+	r, _ := openSimulator(t)
+	data, _ := r.Receive(10 * time.Millisecond)
+	if r.Error() != ErrReceiveTimeout {
+		t.Errorf("Receive() error == %v, want %v", r.Error(), ErrReceiveTimeout)
+	}
+	if data != nil {
+		t.Errorf("Receive() == % X, want nil", data)
+	}
+}
+
+func TestSimulatorReceiveBadCRC(t *testing.T) {
+	r, s := openSimulator(t)
+	s.Inject(SimPacket{Data: p1, RSSI: -70, BadCRC: true})
+	data, _ := r.Receive(time.Second)
+	if r.Error() == nil {
+		t.Errorf("Receive() succeeded with bad CRC")
+	}
+	if data != nil {
+		t.Errorf("Receive() == % X, want nil", data)
+	}
+}
+
+func TestSimulatorSend(t *testing.T) {
+	r, s := openSimulator(t)
+	r.Send(p2)
+	if r.Error() != nil {
+		t.Fatal(r.Error())
+	}
+	sent := s.Transmitted()
+	if len(sent) != 1 || !bytes.Equal(sent[0], p2) {
+		t.Errorf("Transmitted() == % X, want [% X]", sent, p2)
+	}
+}
+
+func TestSimulatorReceiveContext(t *testing.T) {
+	r, _ := openSimulator(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	start := time.Now()
+	r.ReceiveContext(ctx, time.Minute)
+	if r.Error() != context.Canceled {
+		t.Errorf("ReceiveContext() error == %v, want %v", r.Error(), context.Canceled)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("ReceiveContext() took %v after cancellation", time.Since(start))
+	}
+}
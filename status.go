@@ -0,0 +1,84 @@
+package cc2500
+
+import (
+	"sync"
+	"time"
+)
+
+// ReceiverStatus describes the activity of a G4 receiver goroutine
+// started by ReceiveReadings or ReceiveTransmitters.
+type ReceiverStatus struct {
+	Running bool
+	// State is "RX" while listening, including Wake-on-Radio sleep
+	// before listening, "SLEEP" while the radio is in SLEEP state
+	// between readings (see ReceiverOptions.Sleep), and otherwise "IDLE".
+	State        string
+	Channel      int       // index of the current or most recent channel
+	LastRSSI     int       // RSSI of the most recent valid packet, in dBm
+	LastPacket   time.Time // when the most recent valid packet was received
+	Transmitters []TransmitterStatus
+}
+
+// TransmitterStatus describes the sync state and frequency calibration
+// for one G4 transmitter. When readings from any transmitter are accepted,
+// there is a single entry with an empty TransmitterID.
+type TransmitterStatus struct {
+	TransmitterID string
+	InSync        bool
+	LastReading   time.Time // when the packet on channel 0 was sent
+	Offsets       []uint8   // FSCTRL0 value for each channel
+}
+
+// receiverStatus holds the status published by the receiver goroutine,
+// so it can be read safely from other goroutines.
+type receiverStatus struct {
+	mu     sync.Mutex
+	status ReceiverStatus
+}
+
+func (m *receiverStatus) set(status ReceiverStatus) {
+	m.mu.Lock()
+	m.status = status
+	m.mu.Unlock()
+}
+
+func (m *receiverStatus) get() ReceiverStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.status
+}
+
+// ReceiverStatus returns the status of the radio's G4 receiver goroutine.
+// It is safe to call while the receiver is running.
+func (r *Radio) ReceiverStatus() ReceiverStatus {
+	return r.status.get()
+}
+
+// publish makes a copy of the scanner's state available to ReceiverStatus.
+func (s *scanner) publish(state string, channel int) {
+	status := ReceiverStatus{
+		Running:    state != "",
+		State:      state,
+		Channel:    channel,
+		LastRSSI:   s.lastRSSI,
+		LastPacket: s.lastPacket,
+	}
+	if !status.Running {
+		status.State = "IDLE"
+	}
+	if s.any != nil {
+		status.Transmitters = []TransmitterStatus{{
+			Offsets: append([]uint8(nil), s.offsets...),
+		}}
+	}
+	for _, x := range s.xmtrs {
+		status.Transmitters = append(status.Transmitters, TransmitterStatus{
+			TransmitterID: x.id,
+			InSync:        x.inSync,
+			LastReading:   x.lastReading,
+			Offsets:       append([]uint8(nil), x.offsets...),
+		})
+	}
+	s.r.status.set(status)
+	s.opts.Metrics.update(status, time.Now())
+}
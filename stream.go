@@ -0,0 +1,159 @@
+package cc2500
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Packets longer than the 64-byte FIFOs are streamed: the TX FIFO is
+// refilled while the packet is being transmitted, and the RX FIFO is
+// drained while it is being received. The FIFOTHR register sets the
+// thresholds at which this happens (data sheet section 15.3).
+// Since GDO0 signals the sync word and end of packet, the number of
+// bytes in each FIFO is polled rather than signaled on another GDO pin.
+
+const (
+	maxVariableLength = 255
+
+	// Bytes transferred on the air between FIFO polls.
+	pollBytes = 16
+)
+
+// PacketSizeError indicates a packet that is too long to send
+// in the radio's packet length mode.
+type PacketSizeError struct {
+	Length int
+	Max    int
+}
+
+func (e PacketSizeError) Error() string {
+	return fmt.Sprintf("cannot send %d-byte packet (maximum %d)", e.Length, e.Max)
+}
+
+// FIFOThresholds returns the number of bytes in the TX FIFO
+// and in the RX FIFO at which their thresholds are reached.
+func (r *Radio) FIFOThresholds() (int, int) {
+	t := int(r.hw.ReadRegister(FIFOTHR) & FIFOTHR_MASK)
+	return 61 - 4*t, 4 * (t + 1)
+}
+
+// SetFIFOThreshold sets FIFOTHR to the given value (0 to 15).
+// Higher values leave more room in the TX FIFO and less in the RX FIFO
+// before they must be serviced.
+func (r *Radio) SetFIFOThreshold(t byte) {
+	if t > FIFOTHR_MASK {
+		r.SetError(fmt.Errorf("FIFO threshold %d is out of range", t))
+		return
+	}
+	v := r.hw.ReadRegister(FIFOTHR) &^ FIFOTHR_MASK
+	r.hw.WriteRegister(FIFOTHR, v|t)
+}
+
+// Interval between FIFO polls at the radio's data rate.
+func (r *Radio) fifoPoll() time.Duration {
+	_, drate := r.ReadChannelParams()
+	if drate == 0 {
+		return deassertPoll
+	}
+	poll := time.Duration(8 * pollBytes * uint64(time.Second) / uint64(drate))
+	if poll > deassertPoll {
+		poll = deassertPoll
+	}
+	return poll
+}
+
+func (r *Radio) lengthConfig() byte {
+	return r.hw.ReadRegister(PKTCTRL0) & PKTCTRL0_LENGTH_CONFIG_MASK
+}
+
+// In infinite packet length mode, a packet ends when the packet byte
+// counter, modulo 256, reaches PKTLEN after switching to fixed length mode.
+// endInfinite makes the switch for a packet of n bytes, which must happen
+// when fewer than 256 bytes remain, and returns a function that
+// restores infinite packet length mode.
+func (r *Radio) endInfinite(n int) func() {
+	pktlen := r.hw.ReadRegister(PKTLEN)
+	pktctrl0 := r.hw.ReadRegister(PKTCTRL0)
+	r.hw.WriteRegister(PKTLEN, byte(n))
+	r.hw.WriteRegister(PKTCTRL0, pktctrl0&^PKTCTRL0_LENGTH_CONFIG_MASK|PKTCTRL0_LENGTH_CONFIG_FIXED)
+	return func() {
+		err := r.Error()
+		r.hw.WriteRegister(PKTLEN, pktlen)
+		r.hw.WriteRegister(PKTCTRL0, pktctrl0)
+		r.SetError(err)
+	}
+}
+
+// A txStream feeds a packet into the TX FIFO as it is transmitted.
+type txStream struct {
+	r        *Radio
+	packet   []byte // bytes to be written to the TX FIFO
+	written  int
+	infinite bool // whether the radio is in infinite packet length mode
+	restore  func()
+}
+
+// Write as much of the rest of the packet as fits in the given free space.
+// In infinite packet length mode, switch to fixed length mode
+// once the last byte has been written.
+func (t *txStream) fill(free int) {
+	n := len(t.packet) - t.written
+	if n > free {
+		n = free
+	}
+	if n != 0 {
+		t.r.hw.WriteBurst(TXFIFO, t.packet[t.written:t.written+n])
+		t.written += n
+	}
+	if t.infinite && t.restore == nil && t.written == len(t.packet) {
+		t.restore = t.r.endInfinite(len(t.packet))
+	}
+}
+
+// Refill the TX FIFO whenever it drains to the threshold,
+// then wait for the packet to be transmitted.
+// The radio must already be in TX state.
+func (t *txStream) stream() {
+	r := t.r
+	threshold, _ := r.FIFOThresholds()
+	poll := r.fifoPoll()
+	for t.written < len(t.packet) && r.Error() == nil {
+		n := int(r.ReadNumTXBytes())
+		if r.Error() != nil {
+			break
+		}
+		if n > threshold {
+			time.Sleep(poll)
+			continue
+		}
+		t.fill(fifoSize - n)
+	}
+	r.awaitTX()
+}
+
+// Restore the packet length configuration.
+func (t *txStream) close() {
+	if t.restore != nil {
+		t.restore()
+		t.restore = nil
+	}
+}
+
+// ReceiveInfinite is like ReceiveContext for a radio in infinite packet
+// length mode. The packet length n, which may exceed 255 bytes,
+// must be known in advance, for example from a higher-level protocol.
+// The radio is left in infinite packet length mode.
+func (r *Radio) ReceiveInfinite(ctx context.Context, n int, timeout time.Duration) ([]byte, int, uint8) {
+	if r.lengthConfig() != PKTCTRL0_LENGTH_CONFIG_INFINITE {
+		r.SetError(fmt.Errorf("radio is not in infinite packet length mode"))
+		return nil, minRSSI, 0
+	}
+	if n <= 0 {
+		r.SetError(fmt.Errorf("invalid packet length %d", n))
+		return nil, minRSSI, 0
+	}
+	r.Strobe(SRX)
+	defer r.Strobe(SIDLE)
+	return r.receive(ctx, timeout, n)
+}
@@ -0,0 +1,184 @@
+package cc2500
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func longPacket(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	return data
+}
+
+// Stream at a data rate that leaves time to service the FIFOs
+// even when the tests are slowed down.
+func openStreamSimulator(t *testing.T) (*Radio, *Simulator) {
+	r, s := openSimulator(t)
+	r.SetDataRate(20000)
+	return r, s
+}
+
+func setLengthMode(r *Radio, mode LengthMode) {
+	pktctrl0 := r.hw.ReadRegister(PKTCTRL0) &^ PKTCTRL0_LENGTH_CONFIG_MASK
+	r.hw.WriteRegister(PKTCTRL0, pktctrl0|byte(mode))
+}
+
+func TestFIFOThresholds(t *testing.T) {
+	r, _ := openSimulator(t)
+	cases := []struct {
+		thr    byte
+		tx, rx int
+	}{
+		{0, 61, 4},
+		{7, 33, 32},
+		{15, 1, 64},
+	}
+	for _, c := range cases {
+		r.SetFIFOThreshold(c.thr)
+		tx, rx := r.FIFOThresholds()
+		if tx != c.tx || rx != c.rx {
+			t.Errorf("FIFOThresholds() with FIFOTHR = %d == %d, %d, want %d, %d", c.thr, tx, rx, c.tx, c.rx)
+		}
+	}
+	r.SetFIFOThreshold(16)
+	if r.Error() == nil {
+		t.Errorf("SetFIFOThreshold(16) succeeded")
+	}
+}
+
+func TestSendLong(t *testing.T) {
+	for _, n := range []int{63, 64, 100, 255} {
+		r, s := openStreamSimulator(t)
+		data := longPacket(n)
+		r.Send(data)
+		if r.Error() != nil {
+			t.Fatalf("Send(%d bytes): %v", n, r.Error())
+		}
+		sent := s.Transmitted()
+		if len(sent) != 1 || !bytes.Equal(sent[0], data) {
+			t.Errorf("Send(%d bytes) transmitted % X", n, sent)
+		}
+	}
+}
+
+func TestSendTooLong(t *testing.T) {
+	r, s := openSimulator(t)
+	r.Send(longPacket(256))
+	want := PacketSizeError{Length: 256, Max: 255}
+	if r.Error() != want {
+		t.Errorf("Send(256 bytes) error == %v, want %v", r.Error(), want)
+	}
+	if sent := s.Transmitted(); len(sent) != 0 {
+		t.Errorf("Send(256 bytes) transmitted % X", sent)
+	}
+}
+
+func TestReceiveLong(t *testing.T) {
+	r, s := openStreamSimulator(t)
+	data := longPacket(200)
+	s.Inject(SimPacket{Data: data, RSSI: -60})
+	packet, rssi, _ := r.ReceiveContext(context.Background(), time.Second)
+	if r.Error() != nil {
+		t.Fatal(r.Error())
+	}
+	if !bytes.Equal(packet, data) {
+		t.Errorf("ReceiveContext() == % X, want % X", packet, data)
+	}
+	if rssi != -60 {
+		t.Errorf("ReceiveContext() RSSI == %d, want %d", rssi, -60)
+	}
+}
+
+func TestInfiniteLength(t *testing.T) {
+	for _, n := range []int{40, 300} {
+		r, s := openStreamSimulator(t)
+		setLengthMode(r, InfiniteLength)
+		pktlen := r.hw.ReadRegister(PKTLEN)
+		data := longPacket(n)
+		r.Send(data)
+		if r.Error() != nil {
+			t.Fatalf("Send(%d bytes): %v", n, r.Error())
+		}
+		sent := s.Transmitted()
+		if len(sent) != 1 || !bytes.Equal(sent[0], data) {
+			t.Errorf("Send(%d bytes) in infinite mode transmitted %d packets", n, len(sent))
+		}
+		if r.lengthConfig() != PKTCTRL0_LENGTH_CONFIG_INFINITE || r.hw.ReadRegister(PKTLEN) != pktlen {
+			t.Errorf("packet length configuration not restored after Send(%d bytes)", n)
+		}
+		s.Inject(SimPacket{Data: data, RSSI: -60})
+		packet, _, _ := r.ReceiveInfinite(context.Background(), n, time.Second)
+		if r.Error() != nil {
+			t.Fatalf("ReceiveInfinite(%d): %v", n, r.Error())
+		}
+		if !bytes.Equal(packet, data) {
+			t.Errorf("ReceiveInfinite(%d) == % X, want % X", n, packet, data)
+		}
+		if r.lengthConfig() != PKTCTRL0_LENGTH_CONFIG_INFINITE {
+			t.Errorf("infinite length mode not restored after ReceiveInfinite(%d)", n)
+		}
+	}
+}
+
+func TestReceiveInfiniteMode(t *testing.T) {
+	r, _ := openSimulator(t)
+	r.ReceiveInfinite(context.Background(), 100, time.Millisecond)
+	if r.Error() == nil {
+		t.Errorf("ReceiveInfinite() succeeded in variable length mode")
+	}
+}
+
+func TestRXFIFOOverflow(t *testing.T) {
+	r, s := openSimulator(t)
+	m := NewMetrics()
+	r.metrics = m
+	s.Inject(SimPacket{Data: longPacket(100), RSSI: -60})
+	r.Strobe(SRX)
+	// Wait for the packet without draining the RX FIFO.
+	for i := 0; i < 100 && s.ReadRegister(RXBYTES)&RXFIFO_OVERFLOW == 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	r.ReadNumRXBytes()
+	if r.Error() != ErrRXFIFOOverflow {
+		t.Errorf("ReadNumRXBytes() error == %v, want %v", r.Error(), ErrRXFIFOOverflow)
+	}
+	if m.overflows != 1 {
+		t.Errorf("metrics recorded %d overflows, want 1", m.overflows)
+	}
+	if r.State() != "IDLE" {
+		t.Errorf("state after overflow == %s, want IDLE", r.State())
+	}
+	if n := r.ReadNumRXBytes(); n != 0 {
+		t.Errorf("%d bytes in RX FIFO after overflow, want 0", n)
+	}
+}
+
+func TestTXFIFOUnderflow(t *testing.T) {
+	r, s := openStreamSimulator(t)
+	// Promise 100 bytes but supply only 20.
+	r.hw.WriteBurst(TXFIFO, append([]byte{100}, longPacket(20)...))
+	r.Strobe(STX)
+	r.awaitTX()
+	if r.Error() != ErrTXFIFOUnderflow {
+		t.Errorf("awaitTX() error == %v, want %v", r.Error(), ErrTXFIFOUnderflow)
+	}
+	if r.State() != "IDLE" {
+		t.Errorf("state after underflow == %s, want IDLE", r.State())
+	}
+	if sent := s.Transmitted(); len(sent) != 0 {
+		t.Errorf("underflow transmitted % X", sent)
+	}
+	r.SetError(nil)
+	r.Send(p2)
+	if r.Error() != nil {
+		t.Fatalf("Send() after underflow: %v", r.Error())
+	}
+	if sent := s.Transmitted(); len(sent) != 1 || !bytes.Equal(sent[0], p2) {
+		t.Errorf("Transmitted() after underflow == % X, want [% X]", sent, p2)
+	}
+}
@@ -0,0 +1,98 @@
+package cc2500
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RegisterDiff describes a configuration register whose value
+// differs from the one that was written to it.
+type RegisterDiff struct {
+	Register byte
+	Want     byte
+	Actual   byte
+	Fields   []string // names of the fields that differ
+}
+
+func (d RegisterDiff) String() string {
+	return fmt.Sprintf("%s: wrote %02X, read %02X (%s)",
+		RegisterName(d.Register), d.Want, d.Actual, strings.Join(d.Fields, ", "))
+}
+
+// ConfigurationDiff lists the registers that differ between two configurations.
+type ConfigurationDiff []RegisterDiff
+
+func (d ConfigurationDiff) String() string {
+	lines := make([]string, len(d))
+	for i, r := range d {
+		lines[i] = r.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Diff compares the configuration with another one,
+// such as the registers read back from the chip.
+// Fields that the chip updates itself, such as
+// frequency synthesizer calibration results, are ignored.
+func (config *RFConfiguration) Diff(actual *RFConfiguration) ConfigurationDiff {
+	var diff ConfigurationDiff
+	want := config.Bytes()
+	got := actual.Bytes()
+	for i := range want {
+		reg := byte(i)
+		mask := ^volatileMask(reg)
+		if want[i]&mask == got[i]&mask {
+			continue
+		}
+		d := RegisterDiff{Register: reg, Want: want[i], Actual: got[i]}
+		for _, f := range Fields {
+			if f.Register == reg && !f.Volatile && (want[i]^got[i])&f.Mask() != 0 {
+				d.Fields = append(d.Fields, f.Name)
+			}
+		}
+		diff = append(diff, d)
+	}
+	return diff
+}
+
+func volatileMask(reg byte) byte {
+	mask := byte(0)
+	for _, f := range Fields {
+		if f.Register == reg && f.Volatile {
+			mask |= f.Mask()
+		}
+	}
+	return mask
+}
+
+// VerifyError indicates that configuration registers
+// did not take the values written to them.
+type VerifyError struct {
+	Diff ConfigurationDiff
+}
+
+func (e VerifyError) Error() string {
+	regs := make([]string, len(e.Diff))
+	for i, d := range e.Diff {
+		regs[i] = RegisterName(d.Register)
+	}
+	return fmt.Sprintf("configuration not written correctly: %s", strings.Join(regs, ", "))
+}
+
+// VerifyConfiguration reads back the configuration registers
+// and returns their differences from the given configuration.
+func (r *Radio) VerifyConfiguration(config *RFConfiguration) ConfigurationDiff {
+	actual := r.ReadConfiguration()
+	if actual == nil {
+		return nil
+	}
+	return config.Diff(actual)
+}
+
+// SetVerify enables or disables verification of configuration writes.
+// When enabled, WriteConfiguration (and therefore Init, InitRF,
+// InitProfile, and WriteSettings) reads the registers back
+// and sets a VerifyError if any of them differ.
+func (r *Radio) SetVerify(on bool) {
+	r.verify = on
+}
@@ -0,0 +1,63 @@
+package cc2500
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	want := ResetRFConfiguration
+	got := want
+	got.MCSM1 ^= MCSM1_CCA_MODE_RSSI_BELOW_UNLESS_RECEIVING
+	got.FSCAL1 = 0x2A         // calibration result
+	got.FSCAL3 ^= 0x0F        // calibration result
+	got.FSCAL2 ^= 1<<5 | 0x01 // VCO_CORE_H_EN and calibration result
+	diff := want.Diff(&got)
+	expected := ConfigurationDiff{
+		{Register: MCSM1, Want: want.MCSM1, Actual: got.MCSM1, Fields: []string{"CCA_MODE"}},
+		{Register: FSCAL2, Want: want.FSCAL2, Actual: got.FSCAL2, Fields: []string{"VCO_CORE_H_EN"}},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("Diff() ==\n%v\nwant\n%v", diff, expected)
+	}
+	if want.Diff(&want) != nil {
+		t.Errorf("Diff() of identical configurations == %v, want nil", want.Diff(&want))
+	}
+}
+
+func TestVerifyAfterCalibration(t *testing.T) {
+	r, _ := openSimulator(t)
+	r.SetVerify(true)
+	r.Init(BaseFrequency)
+	r.Strobe(SCAL)
+	if r.Error() != nil {
+		t.Fatal(r.Error())
+	}
+	p := G4Profile
+	p.Frequency = BaseFrequency
+	config, err := p.Configuration()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := r.VerifyConfiguration(&config); diff != nil {
+		t.Errorf("VerifyConfiguration() after calibration ==\n%v", diff)
+	}
+}
+
+func TestVerifyStuckRegister(t *testing.T) {
+	r, s := openSimulator(t)
+	s.StickRegister(PKTCTRL1, 0x00)
+	r.Init(BaseFrequency)
+	if r.Error() != nil {
+		t.Fatalf("Init() without verification failed: %v", r.Error())
+	}
+	r.SetVerify(true)
+	r.Init(BaseFrequency)
+	e, ok := r.Error().(VerifyError)
+	if !ok {
+		t.Fatalf("Init() error == %v, want VerifyError", r.Error())
+	}
+	if len(e.Diff) != 1 || e.Diff[0].Register != PKTCTRL1 || e.Diff[0].Actual != 0 {
+		t.Errorf("Init() diff ==\n%v", e.Diff)
+	}
+}
@@ -0,0 +1,159 @@
+package cc2500
+
+import (
+	"context"
+	"time"
+)
+
+// Wake-on-Radio (data sheet section 19.5) lets the chip sleep
+// and wake up periodically on its RC oscillator to listen for packets.
+// Event 0 occurs every EVENT0 * 2^(5*WOR_RES) RC oscillator periods
+// of 750/FXOSC seconds, and the RX timeout set by MCSM2.RX_TIME
+// is EVENT0 * C(RX_TIME, WOR_RES) * 26/X microseconds, where X is
+// the crystal frequency in MHz (see the MCSM2 register description).
+// This is at most 1/8 of the Event 0 timeout when WOR_RES = 0,
+// but only 1/51, 1/910, and 1/20165 for WOR_RES = 1, 2, and 3.
+
+const (
+	maxEvent0 = 0xFFFF
+	maxWORRes = 3
+
+	// Longest RX timeout index; MCSM2_RX_TIME_END_OF_PACKET means no timeout.
+	maxRXTime = MCSM2_RX_TIME_END_OF_PACKET - 1
+
+	// EVENT1 setting for the crystal oscillator to stabilize (48 periods).
+	worEvent1 = 7
+)
+
+func worInterval(event0 uint16, res byte) time.Duration {
+	periods := uint64(event0) << (5 * res)
+	return time.Duration(periods * 750 * 1000000 / (FXOSC / 1000))
+}
+
+// The factors C(RX_TIME, WOR_RES) tabulated in the data sheet
+// are (4*WOR_RES + 1) * 750/26 / 2^(RX_TIME+3), so the RX timeout is
+// (4*WOR_RES + 1) * EVENT0 RC oscillator periods / 2^(RX_TIME+3).
+func worRXTimeout(event0 uint16, res byte, rxTime byte) time.Duration {
+	periods := uint64(4*res+1) * uint64(event0)
+	return time.Duration(periods*750*1000000/(FXOSC/1000)) >> (rxTime + 3)
+}
+
+// The TEST2, TEST1, and TEST0 settings are lost while the chip sleeps
+// between Event 0s, so it listens with their reset values until they are
+// written again. They can be written while the chip is in RX.
+// FSTEST, PTEST, and AGCTEST are also lost, but should only ever
+// have their reset values.
+const numTestRegisters = TEST0 - TEST2 + 1
+
+func (r *Radio) readTestRegisters() []byte {
+	return r.hw.ReadBurst(TEST2, numTestRegisters)
+}
+
+// endWakeOnRadio returns the radio to IDLE and restores the test register
+// values saved before Wake-on-Radio, keeping any error from receiving.
+func (r *Radio) endWakeOnRadio(test []byte) {
+	err := r.Error()
+	r.SetError(nil)
+	r.Strobe(SIDLE)
+	r.hw.WriteBurst(TEST2, test)
+	if err != nil {
+		r.SetError(err)
+	}
+}
+
+// WOREvent0Registers returns the EVENT0 and WORCTRL.WOR_RES values
+// for the given Event 0 timeout, using the finest resolution possible,
+// and the timeout actually achieved.
+func WOREvent0Registers(interval time.Duration) (uint16, byte, time.Duration) {
+	for res := byte(0); res <= maxWORRes; res++ {
+		period := worInterval(1, res)
+		event0 := (interval + period/2) / period
+		if event0 > maxEvent0 {
+			continue
+		}
+		if event0 == 0 {
+			event0 = 1
+		}
+		return uint16(event0), res, worInterval(uint16(event0), res)
+	}
+	return maxEvent0, maxWORRes, worInterval(maxEvent0, maxWORRes)
+}
+
+// WORRXTimeoutRegister returns the MCSM2.RX_TIME value for the shortest
+// RX timeout of at least the given duration, for the Event 0 timeout
+// set by WOREvent0Registers(interval), and the RX timeout actually achieved.
+// If no setting is long enough, the longest RX timeout is returned;
+// it is 1/8 of the Event 0 timeout at best, and much less for intervals
+// longer than about 1.9 seconds.
+func WORRXTimeoutRegister(interval time.Duration, timeout time.Duration) (byte, time.Duration) {
+	event0, res, _ := WOREvent0Registers(interval)
+	for rxTime := byte(maxRXTime); rxTime > 0; rxTime-- {
+		t := worRXTimeout(event0, res, rxTime)
+		if t >= timeout {
+			return rxTime, t
+		}
+	}
+	return 0, worRXTimeout(event0, res, 0)
+}
+
+// SetWakeOnRadio configures Wake-on-Radio to listen for rxTimeout
+// every interval, and returns the interval and RX timeout actually used.
+// An rxTimeout of 0 means no RX timeout: the chip listens after Event 0
+// until it receives a packet or the host ends the listen.
+// The RX timeout also applies to ordinary RX until ClearWakeOnRadio is called.
+func (r *Radio) SetWakeOnRadio(interval time.Duration, rxTimeout time.Duration) (time.Duration, time.Duration) {
+	event0, res, interval := WOREvent0Registers(interval)
+	rxTime := byte(MCSM2_RX_TIME_END_OF_PACKET)
+	if rxTimeout != 0 {
+		rxTime, rxTimeout = WORRXTimeoutRegister(interval, rxTimeout)
+	}
+	r.hw.WriteBurst(WOREVT1, []byte{byte(event0 >> 8), byte(event0)})
+	r.hw.WriteRegister(WORCTRL, worEvent1<<WORCTRL_EVENT1_SHIFT|WORCTRL_RC_CAL|res<<WORCTRL_WOR_RES_SHIFT)
+	mcsm2 := r.hw.ReadRegister(MCSM2)
+	r.hw.WriteRegister(MCSM2, mcsm2&^MCSM2_RX_TIME_MASK|rxTime<<MCSM2_RX_TIME_SHIFT)
+	return interval, rxTimeout
+}
+
+// ClearWakeOnRadio removes the RX timeout and powers down
+// the RC oscillator used for Wake-on-Radio.
+func (r *Radio) ClearWakeOnRadio() {
+	mcsm2 := r.hw.ReadRegister(MCSM2)
+	r.hw.WriteRegister(MCSM2, mcsm2&^MCSM2_RX_TIME_MASK|MCSM2_RX_TIME_END_OF_PACKET)
+	worctrl := r.hw.ReadRegister(WORCTRL)
+	r.hw.WriteRegister(WORCTRL, worctrl|WORCTRL_RC_PD)
+}
+
+// ReceiveWakeOnRadio is like ReceiveContext, but instead of entering RX
+// it starts the Wake-on-Radio sequence configured by SetWakeOnRadio,
+// so that the chip sleeps except while listening after each Event 0.
+// The timeout should allow for at least one Event 0 timeout.
+// The chip listens with the reset TEST2, TEST1, and TEST0 values,
+// so use ReceiveWakeOnRadioAt if the radio depends on other settings.
+// They are restored when the radio is left in IDLE state.
+func (r *Radio) ReceiveWakeOnRadio(ctx context.Context, timeout time.Duration) ([]byte, int, uint8) {
+	test := r.readTestRegisters()
+	r.Strobe(SWORRST)
+	r.Strobe(SWOR)
+	defer r.endWakeOnRadio(test)
+	return r.receive(ctx, timeout, 0)
+}
+
+// ReceiveWakeOnRadioAt starts the Wake-on-Radio sequence configured by
+// SetWakeOnRadio with no RX timeout, and leaves the chip asleep until the
+// wakeup time, which should be after the first Event 0. Since the chip is
+// then listening, the TEST2, TEST1, and TEST0 settings lost in sleep are
+// restored before waiting up to timeout for a packet as ReceiveContext does.
+// The radio is left in IDLE state.
+func (r *Radio) ReceiveWakeOnRadioAt(ctx context.Context, wakeup time.Time, timeout time.Duration) ([]byte, int, uint8) {
+	test := r.readTestRegisters()
+	r.Strobe(SWORRST)
+	r.Strobe(SWOR)
+	defer r.endWakeOnRadio(test)
+	// Accessing the chip would wake it, so just wait.
+	if !syncSleep(ctx, wakeup) {
+		r.SetError(ctx.Err())
+		return nil, minRSSI, 0
+	}
+	r.hw.WriteBurst(TEST2, test)
+	return r.receive(ctx, timeout, 0)
+}
@@ -0,0 +1,197 @@
+package cc2500
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWOREvent0Registers(t *testing.T) {
+	cases := []struct {
+		interval time.Duration
+		event0   uint16
+		res      byte
+	}{
+		{time.Millisecond, 35, 0},
+		{time.Second, 34667, 0},
+		{5 * time.Second, 5417, 1},
+		{5 * time.Minute, 10156, 2},
+		{time.Hour, 3809, 3},
+		{100 * time.Hour, 0xFFFF, 3},
+	}
+	for _, c := range cases {
+		event0, res, actual := WOREvent0Registers(c.interval)
+		if event0 != c.event0 || res != c.res {
+			t.Errorf("WOREvent0Registers(%v) == %d, %d; want %d, %d", c.interval, event0, res, c.event0, c.res)
+		}
+		if actual != worInterval(event0, res) {
+			t.Errorf("WOREvent0Registers(%v) actual == %v, want %v", c.interval, actual, worInterval(event0, res))
+		}
+	}
+}
+
+func TestWORRXTimeoutRegister(t *testing.T) {
+	// An Event 0 timeout of about 470ms, with WOR_RES = 0.
+	interval := worInterval(16384, 0)
+	// About 5 minutes, with WOR_RES = 2.
+	long := worInterval(10156, 2)
+	cases := []struct {
+		interval time.Duration
+		timeout  time.Duration
+		rxTime   byte
+		actual   time.Duration
+	}{
+		{interval, 500 * time.Microsecond, 6, interval >> 9},
+		{interval, 1500 * time.Microsecond, 5, interval >> 8},
+		{interval, 3 * time.Millisecond, 4, interval >> 7},
+		{interval, 50 * time.Millisecond, 0, interval >> 3},
+		{interval, time.Second, 0, interval >> 3},
+		// The longest RX timeout is about 1/910 of the Event 0 timeout.
+		{long, 100 * time.Millisecond, 1, 9 * long >> 14},
+		{long, time.Second, 0, 9 * long >> 13},
+	}
+	for _, c := range cases {
+		rxTime, actual := WORRXTimeoutRegister(c.interval, c.timeout)
+		if rxTime != c.rxTime || !closeTo(actual, c.actual) {
+			t.Errorf("WORRXTimeoutRegister(%v, %v) == %d, %v; want %d, %v", c.interval, c.timeout, rxTime, actual, c.rxTime, c.actual)
+		}
+	}
+	if long < 5*time.Minute-time.Second || long > 5*time.Minute+time.Second {
+		t.Errorf("WOR interval == %v, want about 5m", long)
+	}
+	if _, actual := WORRXTimeoutRegister(long, time.Second); actual > 330*time.Millisecond {
+		t.Errorf("RX timeout for %v Event 0 == %v, want at most 330ms", long, actual)
+	}
+}
+
+// Allow for rounding in the duration computations.
+func closeTo(a, b time.Duration) bool {
+	d := a - b
+	return -time.Microsecond < d && d < time.Microsecond
+}
+
+func TestReceiveWakeOnRadio(t *testing.T) {
+	r, s := openSimulator(t)
+	interval, rxTimeout := r.SetWakeOnRadio(50*time.Millisecond, 5*time.Millisecond)
+	if rxTimeout < 5*time.Millisecond || rxTimeout > interval/8 {
+		t.Errorf("SetWakeOnRadio() RX timeout == %v", rxTimeout)
+	}
+	s.Inject(SimPacket{Data: p1, RSSI: -70})
+	start := time.Now()
+	data, _, _ := r.ReceiveWakeOnRadio(context.Background(), time.Second)
+	if r.Error() != nil {
+		t.Fatal(r.Error())
+	}
+	if !bytes.Equal(data, p1) {
+		t.Errorf("ReceiveWakeOnRadio() == % X, want % X", data, p1)
+	}
+	if time.Since(start) < interval {
+		t.Errorf("ReceiveWakeOnRadio() returned after %v, before Event 0 (%v)", time.Since(start), interval)
+	}
+	if r.hw.ReadRegister(TEST2) != TEST2_RX_LOW_DATA_RATE_MAGIC || r.hw.ReadRegister(TEST1) != TEST1_RX_LOW_DATA_RATE_MAGIC {
+		t.Errorf("test registers not restored after ReceiveWakeOnRadio()")
+	}
+	r.ClearWakeOnRadio()
+	if r.hw.ReadRegister(MCSM2)&MCSM2_RX_TIME_MASK != MCSM2_RX_TIME_END_OF_PACKET {
+		t.Errorf("RX timeout still set after ClearWakeOnRadio()")
+	}
+	if r.hw.ReadRegister(WORCTRL)&WORCTRL_RC_PD == 0 {
+		t.Errorf("RC oscillator still powered after ClearWakeOnRadio()")
+	}
+}
+
+func TestReceiveWakeOnRadioTimeout(t *testing.T) {
+	r, _ := openSimulator(t)
+	interval, _ := r.SetWakeOnRadio(20*time.Millisecond, time.Millisecond)
+	data, _, _ := r.ReceiveWakeOnRadio(context.Background(), 5*interval)
+	if r.Error() != ErrReceiveTimeout {
+		t.Errorf("ReceiveWakeOnRadio() error == %v, want %v", r.Error(), ErrReceiveTimeout)
+	}
+	if data != nil {
+		t.Errorf("ReceiveWakeOnRadio() == % X, want nil", data)
+	}
+	r.SetError(nil)
+	if r.State() != "IDLE" {
+		t.Errorf("state after ReceiveWakeOnRadio() == %s, want IDLE", r.State())
+	}
+	if r.hw.ReadRegister(TEST2) != TEST2_RX_LOW_DATA_RATE_MAGIC {
+		t.Errorf("TEST2 == %02X after Wake-on-Radio timeout", r.hw.ReadRegister(TEST2))
+	}
+}
+
+func TestSetWakeOnRadioNoTimeout(t *testing.T) {
+	r, _ := openSimulator(t)
+	interval, rxTimeout := r.SetWakeOnRadio(5*time.Minute, 0)
+	if rxTimeout != 0 {
+		t.Errorf("SetWakeOnRadio() RX timeout == %v, want 0", rxTimeout)
+	}
+	if interval < 5*time.Minute-time.Second || interval > 5*time.Minute+time.Second {
+		t.Errorf("SetWakeOnRadio() interval == %v, want about 5m", interval)
+	}
+	if r.hw.ReadRegister(MCSM2)&MCSM2_RX_TIME_MASK != MCSM2_RX_TIME_END_OF_PACKET {
+		t.Errorf("SetWakeOnRadio() set an RX timeout")
+	}
+}
+
+func TestScannerWakeOnRadio(t *testing.T) {
+	// The G4 profile's low data rate TEST2 and TEST1 values are lost
+	// in sleep, and must be restored before the packet is received.
+	r, sim := openSimulator(t)
+	opts := ReceiverOptions{WakeOnRadio: true}
+	s := r.newScanner(context.Background(), opts, []string{"67LDE"}, nil)
+	sleep := 300 * time.Millisecond
+	start := time.Now()
+	s.worWakeup = start.Add(sleep)
+	asleep := make(chan bool, 1)
+	time.AfterFunc(sleep/2, func() {
+		sim.mu.Lock()
+		asleep <- sim.wor && sim.state == STATE_IDLE && sim.regs[TEST2] == ResetRFConfiguration.TEST2
+		sim.mu.Unlock()
+	})
+	time.AfterFunc(sleep+10*time.Millisecond, func() {
+		sim.Inject(SimPacket{Data: p1, RSSI: -70})
+	})
+	p, _, err := s.listen(0, 100*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p == nil || p.TransmitterID != "67LDE" {
+		t.Fatalf("listen() == %+v, want packet from 67LDE", p)
+	}
+	if !<-asleep {
+		t.Errorf("radio not in Wake-on-Radio sleep before wakeup")
+	}
+	elapsed := time.Since(start)
+	if elapsed < sleep {
+		t.Errorf("listen() returned after %v, before wakeup", elapsed)
+	}
+	if !s.worWakeup.IsZero() {
+		t.Errorf("Wake-on-Radio wakeup not cleared after listen()")
+	}
+	if r.hw.ReadRegister(TEST2) != TEST2_RX_LOW_DATA_RATE_MAGIC || r.hw.ReadRegister(TEST1) != TEST1_RX_LOW_DATA_RATE_MAGIC {
+		t.Errorf("test registers not restored after Wake-on-Radio listen()")
+	}
+	if r.hw.ReadRegister(MCSM2)&MCSM2_RX_TIME_MASK != MCSM2_RX_TIME_END_OF_PACKET {
+		t.Errorf("RX timeout still set after Wake-on-Radio listen()")
+	}
+	if r.hw.ReadRegister(WORCTRL)&WORCTRL_RC_PD == 0 {
+		t.Errorf("RC oscillator still powered after Wake-on-Radio listen()")
+	}
+}
+
+func TestScannerWakeOnRadioTimeout(t *testing.T) {
+	r, _ := openSimulator(t)
+	s := r.newScanner(context.Background(), ReceiverOptions{WakeOnRadio: true}, []string{"67LDE"}, nil)
+	s.worWakeup = time.Now().Add(50 * time.Millisecond)
+	p, _, err := s.listen(0, 20*time.Millisecond)
+	if err != nil || p != nil {
+		t.Fatalf("listen() == %+v, %v; want nil, nil", p, err)
+	}
+	if r.hw.ReadRegister(TEST2) != TEST2_RX_LOW_DATA_RATE_MAGIC {
+		t.Errorf("TEST2 == %02X after Wake-on-Radio timeout", r.hw.ReadRegister(TEST2))
+	}
+	if r.hw.ReadRegister(MCSM2)&MCSM2_RX_TIME_MASK != MCSM2_RX_TIME_END_OF_PACKET {
+		t.Errorf("RX timeout still set after Wake-on-Radio timeout")
+	}
+}